@@ -2,10 +2,25 @@ package config
 
 import "github.com/alexbakker/gonano/nano/node/proto"
 
+const (
+	StoreBadger = "badger"
+	StoreFile   = "file"
+	StoreMemory = "memory"
+)
+
 type Config struct {
 	Addr      string        `json:"addr"`
 	AddrRPC   string        `json:"addr_rpc"`
 	AddrPprof string        `json:"addr_pprof"`
 	Peers     []string      `json:"peers"`
 	Network   proto.Network `json:"network"`
+	// Store is the database backend to use: "badger", "file" or "memory".
+	Store string `json:"store"`
+	// RepWallet is the path of the encrypted wallet file that holds the
+	// representative account to vote with. Voting is disabled if it's empty.
+	// The password of the wallet is asked for when the node starts.
+	RepWallet string `json:"rep_wallet"`
+	// RepAddress is the address of the representative account in the wallet.
+	// The first account of the wallet is used if it's empty.
+	RepAddress string `json:"rep_address"`
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/alexbakker/gonano/cmd/nano-node/config"
+	"github.com/alexbakker/gonano/nano/store"
+)
+
+// openStore opens the store backend that was selected in the config.
+func openStore() (store.Store, error) {
+	switch cfg.Store {
+	case "", config.StoreBadger:
+		dir := path.Join(man.Dir(), "db")
+		logger.Printf("opening badger database at %s", dir)
+		return store.NewBadgerStore(dir)
+	case config.StoreFile:
+		dir := path.Join(man.Dir(), "filedb")
+		logger.Printf("opening file database at %s", dir)
+		return store.NewFileStore(dir)
+	case config.StoreMemory:
+		logger.Printf("using in-memory database, the ledger will not be persisted")
+		return store.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown store backend: %s", cfg.Store)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"time"
+
+	"github.com/alexbakker/gonano/nano/store"
+	"github.com/alexbakker/gonano/nano/store/genesis"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ledgerCmd = &cobra.Command{
+		Use:   "ledger",
+		Short: "Manage the ledger",
+	}
+	ledgerExportCmd = &cobra.Command{
+		Use:   "export <file>",
+		Short: "Export all blocks in the ledger to a snapshot file",
+		Args:  cobra.ExactArgs(1),
+		Run:   exportLedger,
+	}
+	ledgerImportCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import the blocks in a snapshot file into the ledger",
+		Args:  cobra.ExactArgs(1),
+		Run:   importLedger,
+	}
+	ledgerCheckCmd = &cobra.Command{
+		Use:   "check",
+		Short: "Check the consistency of the ledger",
+		Args:  cobra.NoArgs,
+		Run:   checkLedger,
+	}
+
+	checkRepair bool
+)
+
+func init() {
+	ledgerCmd.AddCommand(ledgerExportCmd)
+	ledgerCmd.AddCommand(ledgerImportCmd)
+	ledgerCmd.AddCommand(ledgerCheckCmd)
+	ledgerCheckCmd.Flags().BoolVar(&checkRepair, "repair", false, "repair the issues that are found")
+	rootCmd.AddCommand(ledgerCmd)
+}
+
+// openLedger opens the database and initializes the ledger on top of it.
+func openLedger() (store.Store, *store.Ledger) {
+	gen, err := genesis.Get(cfg.Network)
+	if err != nil {
+		logger.Fatalf("error obtaining genesis info: %s", err)
+	}
+
+	db, err := openStore()
+	if err != nil {
+		logger.Fatalf("error opening database: %s", err)
+	}
+
+	ledger, err := store.NewLedger(db, store.LedgerOptions{Genesis: gen})
+	if err != nil {
+		db.Close()
+		logger.Fatalf("error initializing ledger: %s", err)
+	}
+
+	return db, ledger
+}
+
+func exportLedger(cmd *cobra.Command, args []string) {
+	db, ledger := openLedger()
+	defer db.Close()
+
+	file, err := os.Create(args[0])
+	if err != nil {
+		logger.Fatalf("error creating snapshot file: %s", err)
+	}
+
+	start := time.Now()
+	writer := bufio.NewWriter(file)
+	count, err := ledger.ExportSnapshot(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(args[0])
+		db.Close()
+		logger.Fatalf("error exporting ledger: %s", err)
+	}
+
+	logger.Printf("exported %d blocks to %s in %s", count, args[0], time.Since(start))
+}
+
+func importLedger(cmd *cobra.Command, args []string) {
+	db, ledger := openLedger()
+	defer db.Close()
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		logger.Fatalf("error opening snapshot file: %s", err)
+	}
+	defer file.Close()
+
+	start := time.Now()
+	count, err := ledger.ImportSnapshot(file)
+	if err != nil {
+		db.Close()
+		logger.Fatalf("error importing ledger after %d blocks: %s", count, err)
+	}
+
+	logger.Printf("imported %d blocks from %s in %s", count, args[0], time.Since(start))
+}
+
+func checkLedger(cmd *cobra.Command, args []string) {
+	db, ledger := openLedger()
+	defer db.Close()
+
+	start := time.Now()
+	issues, err := ledger.Check(checkRepair)
+	if err != nil {
+		db.Close()
+		logger.Fatalf("error checking ledger: %s", err)
+	}
+
+	for _, issue := range issues {
+		logger.Println(issue)
+	}
+	logger.Printf("found %d issues in %s", len(issues), time.Since(start))
+
+	if len(issues) > 0 {
+		if checkRepair {
+			logger.Printf("repaired the ledger (issues with the account chains themselves are not repaired)")
+		} else {
+			db.Close()
+			os.Exit(1)
+		}
+	}
+}
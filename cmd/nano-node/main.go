@@ -4,7 +4,6 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"path"
 	"syscall"
 
 	"github.com/alexbakker/gonano/cmd/nano-node/config"
@@ -30,6 +29,7 @@ var (
 			"rai.raiblocks.net:7075",
 		},
 		Network: proto.NetworkLive,
+		Store:   config.StoreBadger,
 	}
 
 	logger = log.New(os.Stdout, "", log.Ldate|log.Lmicroseconds)
@@ -81,8 +81,12 @@ func startNode(cmd *cobra.Command, args []string) {
 	nodeOpts.Address = cfg.Addr
 	nodeOpts.Network = cfg.Network
 
-	logger.Printf("opening badger database at %s", man.Dir())
-	db, err := store.NewBadgerStore(path.Join(man.Dir(), "db"))
+	if cfg.RepWallet != "" {
+		nodeOpts.Representative = loadRepresentative()
+		logger.Printf("voting as representative %s", nodeOpts.Representative.Address())
+	}
+
+	db, err := openStore()
 	if err != nil {
 		logger.Fatalf("error opening database: %s", err)
 	}
@@ -99,6 +103,7 @@ func startNode(cmd *cobra.Command, args []string) {
 	if err != nil {
 		logger.Fatalf("error initializing node: %s", err)
 	}
+	startRPC(ledger, nanode)
 	go func() {
 		logger.Printf("starting node (network: %s)", nodeOpts.Network)
 		if err := nanode.Run(); err != nil {
@@ -119,7 +124,7 @@ func startNode(cmd *cobra.Command, args []string) {
 				logger.Printf("error stopping node: %s", err)
 			}
 
-			logger.Println("closing badger database")
+			logger.Println("closing database")
 			if err := db.Close(); err != nil {
 				logger.Printf("error closing db: %s", err)
 			}
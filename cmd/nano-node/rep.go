@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/wallet"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// loadRepresentative asks for the password of the representative wallet and
+// returns the account to vote with.
+func loadRepresentative() *wallet.Account {
+	password := readPassword(fmt.Sprintf("Password of %s: ", cfg.RepWallet))
+	w, err := wallet.Load(cfg.RepWallet, password)
+	if err != nil {
+		logger.Fatalf("error loading representative wallet: %s", err)
+	}
+
+	if cfg.RepAddress == "" {
+		accounts := w.Accounts()
+		if len(accounts) == 0 {
+			logger.Fatalf("representative wallet has no accounts")
+		}
+		return accounts[0]
+	}
+
+	address, err := nano.ParseAddress(cfg.RepAddress)
+	if err != nil {
+		logger.Fatalf("bad representative address: %s", err)
+	}
+
+	acc := w.Account(address)
+	if acc == nil {
+		logger.Fatalf("representative %s is not in the wallet", address)
+	}
+
+	return acc
+}
+
+// readPassword reads a password from the terminal without echoing it. If stdin
+// is not a terminal, a line is read from it instead.
+func readPassword(prompt string) string {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			logger.Fatalf("error reading password: %s", err)
+		}
+		return strings.TrimRight(line, "\r\n")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		logger.Fatalf("error reading password: %s", err)
+	}
+
+	return string(password)
+}
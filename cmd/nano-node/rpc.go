@@ -0,0 +1,21 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/alexbakker/gonano/cmd/nano-node/rpc"
+	"github.com/alexbakker/gonano/nano/node"
+	"github.com/alexbakker/gonano/nano/store"
+)
+
+func startRPC(ledger *store.Ledger, nanode *node.Node) {
+	if cfg.AddrRPC == "" {
+		return
+	}
+
+	logger.Printf("starting rpc http server at %s", cfg.AddrRPC)
+
+	go func() {
+		logger.Printf("error running rpc: %s", http.ListenAndServe(cfg.AddrRPC, rpc.New(ledger, nanode)))
+	}()
+}
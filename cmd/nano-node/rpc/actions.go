@@ -0,0 +1,532 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/store"
+)
+
+const (
+	defaultCount = 1000
+)
+
+var (
+	// errStop is used to stop walking the ledger early
+	errStop = errors.New("stop")
+)
+
+type accountRequest struct {
+	Account nano.Address `json:"account"`
+}
+
+type accountBalanceResponse struct {
+	Balance Raw `json:"balance"`
+	Pending Raw `json:"pending"`
+}
+
+func (s *Server) accountBalance(data []byte) (interface{}, error) {
+	var req accountRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	balance, err := s.ledger.GetBalance(req.Account)
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+
+	pending, err := s.pendingBalance(req.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	return &accountBalanceResponse{
+		Balance: Raw(balance),
+		Pending: Raw(pending),
+	}, nil
+}
+
+// pendingBalance returns the sum of all pending transactions of the given
+// address.
+func (s *Server) pendingBalance(address nano.Address) (nano.Balance, error) {
+	balance := nano.ZeroBalance
+
+	err := s.ledger.WalkPending(address, func(hash block.Hash, pending *store.Pending) error {
+		balance = balance.Add(pending.Amount)
+		return nil
+	})
+
+	return balance, err
+}
+
+type accountInfoRequest struct {
+	Account        nano.Address `json:"account"`
+	Representative Bool         `json:"representative"`
+	Weight         Bool         `json:"weight"`
+	Pending        Bool         `json:"pending"`
+}
+
+type accountInfoResponse struct {
+	Frontier            block.Hash    `json:"frontier"`
+	OpenBlock           block.Hash    `json:"open_block"`
+	RepresentativeBlock block.Hash    `json:"representative_block"`
+	Balance             Raw           `json:"balance"`
+	BlockCount          Uint64        `json:"block_count"`
+	AccountVersion      Uint64        `json:"account_version"`
+	Representative      *nano.Address `json:"representative,omitempty"`
+	Weight              *Raw          `json:"weight,omitempty"`
+	Pending             *Raw          `json:"pending,omitempty"`
+}
+
+func (s *Server) accountInfo(data []byte) (interface{}, error) {
+	var req accountInfoRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	info, err := s.ledger.GetAddressInfo(req.Account)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	res := accountInfoResponse{
+		Frontier:            info.HeadBlock,
+		OpenBlock:           info.OpenBlock,
+		RepresentativeBlock: info.RepBlock,
+		Balance:             Raw(info.Balance),
+		BlockCount:          Uint64(info.BlockCount),
+		AccountVersion:      Uint64(info.Epoch),
+	}
+
+	if req.Representative {
+		rep, err := s.ledger.GetRepresentative(req.Account)
+		if err != nil {
+			return nil, err
+		}
+		res.Representative = &rep
+	}
+
+	if req.Weight {
+		weight, err := s.ledger.GetRepresentation(req.Account)
+		if err != nil {
+			return nil, err
+		}
+		res.Weight = (*Raw)(&weight)
+	}
+
+	if req.Pending {
+		pending, err := s.pendingBalance(req.Account)
+		if err != nil {
+			return nil, err
+		}
+		res.Pending = (*Raw)(&pending)
+	}
+
+	return &res, nil
+}
+
+type accountHistoryRequest struct {
+	Account nano.Address `json:"account"`
+	Count   Uint64       `json:"count"`
+	Head    *block.Hash  `json:"head"`
+	Reverse Bool         `json:"reverse"`
+}
+
+type accountHistoryResponse struct {
+	Account  nano.Address          `json:"account"`
+	History  []*accountHistoryItem `json:"history"`
+	Previous *block.Hash           `json:"previous,omitempty"`
+	Next     *block.Hash           `json:"next,omitempty"`
+}
+
+type accountHistoryItem struct {
+	Type           string       `json:"type"`
+	Subtype        string       `json:"subtype"`
+	Account        nano.Address `json:"account"`
+	Amount         Raw          `json:"amount"`
+	Hash           block.Hash   `json:"hash"`
+	Height         Uint64       `json:"height"`
+	LocalTimestamp Uint64       `json:"local_timestamp"`
+}
+
+func (s *Server) accountHistory(data []byte) (interface{}, error) {
+	req := accountHistoryRequest{Count: defaultCount}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	var start block.Hash
+	if req.Head != nil {
+		start = *req.Head
+	}
+
+	// fetch one extra entry to find out where the next page starts
+	entries, err := s.ledger.AccountHistory(req.Account, start, int(req.Count)+1, bool(req.Reverse))
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			return nil, ErrAccountNotFound
+		case store.ErrNotInChain:
+			return nil, ErrBlockNotFound
+		}
+		return nil, err
+	}
+
+	res := accountHistoryResponse{
+		Account: req.Account,
+		History: []*accountHistoryItem{},
+	}
+
+	for i, entry := range entries {
+		if uint64(i) == uint64(req.Count) {
+			if req.Reverse {
+				res.Next = &entry.Hash
+			} else {
+				res.Previous = &entry.Hash
+			}
+			break
+		}
+
+		// open blocks are receives from the perspective of the user
+		typ := entry.Subtype.String()
+		if entry.Subtype == block.SubtypeOpen {
+			typ = block.SubtypeReceive.String()
+		}
+
+		res.History = append(res.History, &accountHistoryItem{
+			Type:           typ,
+			Subtype:        entry.Subtype.String(),
+			Account:        entry.Account,
+			Amount:         Raw(entry.Amount),
+			Hash:           entry.Hash,
+			Height:         Uint64(entry.Height),
+			LocalTimestamp: Uint64(entry.Timestamp),
+		})
+	}
+
+	return &res, nil
+}
+
+type blockInfoRequest struct {
+	Hash      block.Hash `json:"hash"`
+	JSONBlock Bool       `json:"json_block"`
+}
+
+type blocksInfoRequest struct {
+	Hashes    []block.Hash `json:"hashes"`
+	JSONBlock Bool         `json:"json_block"`
+}
+
+type blockInfoResponse struct {
+	BlockAccount   nano.Address `json:"block_account"`
+	Amount         Raw          `json:"amount"`
+	Balance        Raw          `json:"balance"`
+	Height         Uint64       `json:"height"`
+	LocalTimestamp Uint64       `json:"local_timestamp"`
+	Successor      block.Hash   `json:"successor"`
+	Confirmed      bool         `json:"confirmed,string"`
+	Contents       interface{}  `json:"contents"`
+}
+
+type blocksInfoResponse struct {
+	Blocks map[block.Hash]*blockInfoResponse `json:"blocks"`
+}
+
+func (s *Server) blockInfo(data []byte) (interface{}, error) {
+	var req blockInfoRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	return s.getBlockInfo(req.Hash, bool(req.JSONBlock))
+}
+
+func (s *Server) blocksInfo(data []byte) (interface{}, error) {
+	var req blocksInfoRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	res := blocksInfoResponse{Blocks: map[block.Hash]*blockInfoResponse{}}
+	for _, hash := range req.Hashes {
+		info, err := s.getBlockInfo(hash, bool(req.JSONBlock))
+		if err != nil {
+			return nil, err
+		}
+		res.Blocks[hash] = info
+	}
+
+	return &res, nil
+}
+
+func (s *Server) getBlockInfo(hash block.Hash, jsonBlock bool) (*blockInfoResponse, error) {
+	blk, err := s.ledger.GetBlock(hash)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, ErrBlockNotFound
+		}
+		return nil, err
+	}
+
+	sideband, err := s.ledger.GetSideband(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := s.ledger.GetBlockAmount(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmed, err := s.ledger.Confirmed(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := blockContents(blk, jsonBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blockInfoResponse{
+		BlockAccount:   sideband.Account,
+		Amount:         Raw(amount),
+		Balance:        Raw(sideband.Balance),
+		Height:         Uint64(sideband.Height),
+		LocalTimestamp: Uint64(sideband.Timestamp),
+		Successor:      sideband.Successor,
+		Confirmed:      confirmed,
+		Contents:       contents,
+	}, nil
+}
+
+type pendingRequest struct {
+	Account   nano.Address `json:"account"`
+	Count     Uint64       `json:"count"`
+	Threshold *Raw         `json:"threshold"`
+	Source    Bool         `json:"source"`
+	Sorting   Bool         `json:"sorting"`
+}
+
+type pendingSource struct {
+	Amount Raw          `json:"amount"`
+	Source nano.Address `json:"source"`
+}
+
+func (s *Server) pending(data []byte) (interface{}, error) {
+	req := pendingRequest{Count: defaultCount}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	threshold := nano.ZeroBalance
+	if req.Threshold != nil {
+		threshold = nano.Balance(*req.Threshold)
+	}
+
+	entries, err := s.ledger.Pending(req.Account, threshold, int(req.Count), bool(req.Sorting))
+	if err != nil {
+		return nil, err
+	}
+
+	// the format of the response depends on the requested details
+	blocks := newOrderedMap()
+	switch {
+	case bool(req.Source):
+		for _, entry := range entries {
+			blocks.Set(entry.Hash, &pendingSource{
+				Amount: Raw(entry.Amount),
+				Source: entry.Source,
+			})
+		}
+	case req.Threshold != nil:
+		for _, entry := range entries {
+			blocks.Set(entry.Hash, Raw(entry.Amount))
+		}
+	default:
+		hashes := []block.Hash{}
+		for _, entry := range entries {
+			hashes = append(hashes, entry.Hash)
+		}
+		return map[string]interface{}{"blocks": hashes}, nil
+	}
+
+	return map[string]interface{}{"blocks": blocks}, nil
+}
+
+type processRequest struct {
+	Block json.RawMessage `json:"block"`
+}
+
+type processResponse struct {
+	Hash block.Hash `json:"hash"`
+}
+
+func (s *Server) process(data []byte) (interface{}, error) {
+	var req processRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	blk, err := parseBlock(req.Block)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.ledger.AddBlock(blk)
+	if err != nil {
+		return nil, err
+	}
+	if res != store.ProcessProgress {
+		return nil, fmt.Errorf("block rejected: %s", res)
+	}
+
+	// the block was added to our ledger, so the caller still needs its hash
+	// if publishing it fails
+	if err := s.node.Publish(blk); err != nil {
+		fmt.Printf("error publishing block %s: %s\n", blk.Hash(), err)
+	}
+
+	return &processResponse{Hash: blk.Hash()}, nil
+}
+
+type blockCountResponse struct {
+	Count     Uint64 `json:"count"`
+	Unchecked Uint64 `json:"unchecked"`
+}
+
+func (s *Server) blockCount(data []byte) (interface{}, error) {
+	count, err := s.ledger.CountBlocks()
+	if err != nil {
+		return nil, err
+	}
+
+	unchecked, err := s.ledger.CountUncheckedBlocks()
+	if err != nil {
+		return nil, err
+	}
+
+	return &blockCountResponse{
+		Count:     Uint64(count),
+		Unchecked: Uint64(unchecked),
+	}, nil
+}
+
+type frontiersRequest struct {
+	Account nano.Address `json:"account"`
+	Count   Uint64       `json:"count"`
+}
+
+type frontiersResponse struct {
+	Frontiers map[nano.Address]block.Hash `json:"frontiers"`
+}
+
+func (s *Server) frontiers(data []byte) (interface{}, error) {
+	req := frontiersRequest{Count: defaultCount}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	res := frontiersResponse{Frontiers: map[nano.Address]block.Hash{}}
+	err := s.ledger.WalkFrontiers(req.Account, time.Time{}, func(frontier *block.Frontier) error {
+		if uint64(len(res.Frontiers)) >= uint64(req.Count) {
+			return errStop
+		}
+
+		res.Frontiers[frontier.Address] = frontier.Hash
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+type representativesRequest struct {
+	Count   Uint64 `json:"count"`
+	Sorting Bool   `json:"sorting"`
+}
+
+func (s *Server) representatives(data []byte) (interface{}, error) {
+	req := representativesRequest{Count: defaultCount}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	// representatives are visited in order of their address, so unless
+	// sorting by weight was requested, we can stop once we have enough of them
+	var reps []*store.Representative
+	err := s.ledger.WalkRepresentations(func(address nano.Address, weight nano.Balance) error {
+		if !req.Sorting && uint64(len(reps)) >= uint64(req.Count) {
+			return errStop
+		}
+
+		if !weight.Equal(nano.ZeroBalance) {
+			reps = append(reps, &store.Representative{Address: address, Weight: weight})
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nil, err
+	}
+
+	if req.Sorting {
+		sort.SliceStable(reps, func(i, j int) bool {
+			return reps[i].Weight.Compare(reps[j].Weight) == nano.BalanceCompBigger
+		})
+	}
+
+	if uint64(len(reps)) > uint64(req.Count) {
+		reps = reps[:req.Count]
+	}
+
+	res := newOrderedMap()
+	for _, rep := range reps {
+		res.Set(rep.Address, Raw(rep.Weight))
+	}
+
+	return map[string]interface{}{"representatives": res}, nil
+}
+
+type peersResponse struct {
+	Peers map[string]string `json:"peers"`
+}
+
+// peers returns the protocol version of every peer. Peers we haven't received
+// a packet from yet are left out, as we don't know their version.
+func (s *Server) peers(data []byte) (interface{}, error) {
+	res := peersResponse{Peers: map[string]string{}}
+	for _, peer := range s.node.Peers() {
+		if version := peer.Version(); version != 0 {
+			res.Peers[peer.Addr.String()] = fmt.Sprintf("%d", version)
+		}
+	}
+
+	return &res, nil
+}
+
+type versionResponse struct {
+	RPCVersion      string `json:"rpc_version"`
+	StoreVersion    string `json:"store_version"`
+	ProtocolVersion string `json:"protocol_version"`
+	NodeVendor      string `json:"node_vendor"`
+}
+
+func (s *Server) version(data []byte) (interface{}, error) {
+	return &versionResponse{
+		RPCVersion:      Version,
+		StoreVersion:    fmt.Sprintf("%d", store.SchemaVersion),
+		ProtocolVersion: fmt.Sprintf("%d", s.node.Versions().Using),
+		NodeVendor:      "gonano",
+	}, nil
+}
@@ -0,0 +1,134 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+// call sends the given request to the server and returns the raw response.
+func (s *testServer) call(t *testing.T, req string) string {
+	res, err := http.Post(s.URL, "application/json", bytes.NewReader([]byte(req)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(bytes.TrimSpace(data))
+}
+
+// process adds the given blocks to the ledger of the server.
+func (s *testServer) process(t *testing.T, blocks ...block.Block) {
+	for _, blk := range blocks {
+		if _, err := NewClient(s.URL).Process(context.Background(), blk); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRepresentatives(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close(t)
+
+	gen, genAcc := server.genesis, server.genAcc
+	acc := newTestAccount(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+
+	send := &block.StateBlock{
+		Address:        genAcc.address,
+		PreviousHash:   gen.Block.Hash(),
+		Representative: genAcc.address,
+		Balance:        gen.Balance.Sub(amount),
+		Link:           block.Hash(acc.address),
+	}
+	genAcc.sign(&send.Signature, send.Hash())
+	open := &block.StateBlock{
+		Address:        acc.address,
+		Representative: acc.address,
+		Balance:        amount,
+		Link:           send.Hash(),
+	}
+	acc.sign(&open.Signature, open.Hash())
+	server.process(t, send, open)
+
+	genRep := fmt.Sprintf(`"%s":"%s"`, genAcc.address, send.Balance.BigInt())
+	accRep := fmt.Sprintf(`"%s":"%s"`, acc.address, amount.BigInt())
+	first, second := genRep, accRep
+	if bytes.Compare(acc.address[:], genAcc.address[:]) < 0 {
+		first, second = accRep, genRep
+	}
+
+	tests := []struct {
+		req string
+		res string
+	}{
+		{`{"action":"representatives"}`, fmt.Sprintf(`{"representatives":{%s,%s}}`, first, second)},
+		{`{"action":"representatives","count":"1"}`, fmt.Sprintf(`{"representatives":{%s}}`, first)},
+		{`{"action":"representatives","sorting":"true"}`, fmt.Sprintf(`{"representatives":{%s,%s}}`, genRep, accRep)},
+		{`{"action":"representatives","sorting":"true","count":"1"}`, fmt.Sprintf(`{"representatives":{%s}}`, genRep)},
+	}
+
+	for _, test := range tests {
+		if res := server.call(t, test.req); res != test.res {
+			t.Fatalf("unexpected response to %s: %s", test.req, res)
+		}
+	}
+}
+
+func TestBlockInfo(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close(t)
+
+	gen, genAcc := server.genesis, server.genAcc
+	send := &block.StateBlock{
+		Address:        genAcc.address,
+		PreviousHash:   gen.Block.Hash(),
+		Representative: genAcc.address,
+		Balance:        gen.Balance.Sub(nano.ParseBalanceInts(0, 1000)),
+		Link:           block.Hash(newTestAccount(t).address),
+	}
+	genAcc.sign(&send.Signature, send.Hash())
+	server.process(t, send)
+
+	// the contents are a string containing a JSON object, unless json_block is set
+	tests := []struct {
+		jsonBlock string
+		contents  string
+	}{
+		{`false`, `"{\"type\":\"state\"`},
+		{`"true"`, `{"type":"state"`},
+	}
+
+	for _, test := range tests {
+		req := fmt.Sprintf(`{"action":"block_info","hash":"%s","json_block":%s}`, send.Hash(), test.jsonBlock)
+		var res struct {
+			Contents json.RawMessage `json:"contents"`
+		}
+		if err := json.Unmarshal([]byte(server.call(t, req)), &res); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(res.Contents, []byte(test.contents)) {
+			t.Fatalf("unexpected contents for json_block %s: %s", test.jsonBlock, res.Contents)
+		}
+
+		blk, err := parseBlock(res.Contents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if blk.Hash() != send.Hash() {
+			t.Fatalf("unexpected block: %s", blk.Hash())
+		}
+	}
+}
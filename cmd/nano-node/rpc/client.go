@@ -0,0 +1,170 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/store"
+)
+
+// Client is a client for the RPC interface of a node. Only the actions that a
+// wallet needs are implemented.
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+// AccountInfo is the information of an account returned by the account_info
+// action.
+type AccountInfo struct {
+	Frontier       block.Hash
+	Balance        nano.Balance
+	Representative nano.Address
+	Epoch          block.Epoch
+}
+
+// NewClient creates a new client for the RPC interface at the given URL.
+func NewClient(url string) *Client {
+	return &Client{
+		url:    url,
+		client: &http.Client{},
+	}
+}
+
+// AccountInfo returns the information of the given account.
+// ErrAccountNotFound is returned if the account isn't opened yet.
+func (c *Client) AccountInfo(ctx context.Context, address nano.Address) (*AccountInfo, error) {
+	req := map[string]interface{}{
+		"action":         "account_info",
+		"account":        address,
+		"representative": true,
+	}
+
+	var res accountInfoResponse
+	if err := c.call(ctx, req, &res); err != nil {
+		return nil, err
+	}
+	if res.Representative == nil {
+		return nil, fmt.Errorf("no representative in account_info response")
+	}
+
+	return &AccountInfo{
+		Frontier:       res.Frontier,
+		Balance:        nano.Balance(res.Balance),
+		Representative: *res.Representative,
+		Epoch:          block.Epoch(res.AccountVersion),
+	}, nil
+}
+
+// Pending returns up to count pending transactions of the given account,
+// ordered by hash.
+func (c *Client) Pending(ctx context.Context, address nano.Address, count uint64) ([]*store.PendingEntry, error) {
+	req := map[string]interface{}{
+		"action":  "pending",
+		"account": address,
+		"count":   Uint64(count),
+		"source":  true,
+	}
+
+	var res struct {
+		Blocks json.RawMessage `json:"blocks"`
+	}
+	if err := c.call(ctx, req, &res); err != nil {
+		return nil, err
+	}
+
+	// the reference node returns an empty string if there are no blocks
+	var blocks map[block.Hash]*pendingSource
+	if string(res.Blocks) != `""` {
+		if err := json.Unmarshal(res.Blocks, &blocks); err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []*store.PendingEntry
+	for hash, source := range blocks {
+		entries = append(entries, &store.PendingEntry{
+			Hash:   hash,
+			Source: source.Source,
+			Amount: nano.Balance(source.Amount),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Hash[:], entries[j].Hash[:]) < 0
+	})
+
+	return entries, nil
+}
+
+// Process adds the given block to the ledger of the node and publishes it.
+func (c *Client) Process(ctx context.Context, blk block.Block) (block.Hash, error) {
+	contents, err := newBlockJSON(blk)
+	if err != nil {
+		return block.Hash{}, err
+	}
+
+	req := map[string]interface{}{
+		"action": "process",
+		"block":  contents,
+	}
+
+	var res processResponse
+	if err := c.call(ctx, req, &res); err != nil {
+		return block.Hash{}, err
+	}
+
+	return res.Hash, nil
+}
+
+// call sends the given request to the node and decodes the response into res.
+// If the response has an error field, it's returned as an error.
+func (c *Client) call(ctx context.Context, req interface{}, res interface{}) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpRes, err := c.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+
+	data, err = ioutil.ReadAll(httpRes.Body)
+	if err != nil {
+		return err
+	}
+	if httpRes.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected rpc status: %s", httpRes.Status)
+	}
+
+	var errRes errorResponse
+	if err := json.Unmarshal(data, &errRes); err != nil {
+		return err
+	}
+	switch errRes.Error {
+	case "":
+	case ErrAccountNotFound.Error():
+		return ErrAccountNotFound
+	case ErrBlockNotFound.Error():
+		return ErrBlockNotFound
+	default:
+		return fmt.Errorf("rpc error: %s", errRes.Error)
+	}
+
+	return json.Unmarshal(data, res)
+}
@@ -0,0 +1,174 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/crypto/ed25519"
+	"github.com/alexbakker/gonano/nano/node"
+	"github.com/alexbakker/gonano/nano/store"
+	"github.com/alexbakker/gonano/nano/store/genesis"
+)
+
+type testAccount struct {
+	address nano.Address
+	key     ed25519.PrivateKey
+}
+
+type testServer struct {
+	*httptest.Server
+	genesis genesis.Genesis
+	genAcc  *testAccount
+	node    *node.Node
+}
+
+func newTestAccount(t *testing.T) *testAccount {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var address nano.Address
+	copy(address[:], key.Public().(ed25519.PublicKey))
+	return &testAccount{address: address, key: key}
+}
+
+func (a *testAccount) sign(sig *block.Signature, hash block.Hash) {
+	copy(sig[:], ed25519.Sign(a.key, hash[:]))
+}
+
+// newTestServer starts an RPC server for a node with an in-memory ledger. The
+// genesis block of the ledger has a work threshold of zero.
+func newTestServer(t *testing.T) *testServer {
+	genAcc := newTestAccount(t)
+	blk := block.OpenBlock{
+		SourceHash:     block.Hash(genAcc.address),
+		Representative: genAcc.address,
+		Address:        genAcc.address,
+	}
+	genAcc.sign(&blk.Signature, blk.Hash())
+	gen := genesis.Genesis{
+		Block:   blk,
+		Balance: nano.ParseBalanceInts(0xffffffffffffffff, 0xffffffffffffffff),
+	}
+
+	ledger, err := store.NewLedger(store.NewMemoryStore(), store.LedgerOptions{Genesis: gen})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := node.DefaultOptions
+	opts.Address = "127.0.0.1:0"
+	opts.EnableVoting = false
+	n, err := node.New(ledger, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testServer{
+		Server:  httptest.NewServer(New(ledger, n)),
+		genesis: gen,
+		genAcc:  genAcc,
+		node:    n,
+	}
+}
+
+func (s *testServer) Close(t *testing.T) {
+	s.Server.Close()
+	if err := s.node.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientAccountNotFound(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close(t)
+
+	client := NewClient(server.URL)
+	if _, err := client.AccountInfo(context.Background(), newTestAccount(t).address); err != ErrAccountNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestClientPendingEmpty(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close(t)
+
+	entries, err := NewClient(server.URL).Pending(context.Background(), newTestAccount(t).address, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("unexpected pending entries: %d", len(entries))
+	}
+
+	// the reference node returns an empty string instead of an empty object
+	reference := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"blocks": ""}`))
+	}))
+	defer reference.Close()
+
+	entries, err = NewClient(reference.URL).Pending(context.Background(), newTestAccount(t).address, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("unexpected pending entries: %d", len(entries))
+	}
+}
+
+func TestClientProcess(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close(t)
+
+	ctx := context.Background()
+	client := NewClient(server.URL)
+	gen, genAcc := server.genesis, server.genAcc
+	acc := newTestAccount(t)
+
+	amount := nano.ParseBalanceInts(0, 1000)
+	send := &block.StateBlock{
+		Address:        genAcc.address,
+		PreviousHash:   gen.Block.Hash(),
+		Representative: genAcc.address,
+		Balance:        gen.Balance.Sub(amount),
+		Link:           block.Hash(acc.address),
+	}
+	genAcc.sign(&send.Signature, send.Hash())
+
+	hash, err := client.Process(ctx, send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != send.Hash() {
+		t.Fatalf("unexpected hash: %s != %s", hash, send.Hash())
+	}
+
+	// processing the same block again is rejected
+	if _, err := client.Process(ctx, send); err == nil {
+		t.Fatal("expected an error processing the same block twice")
+	}
+
+	info, err := client.AccountInfo(ctx, genAcc.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Frontier != send.Hash() || !info.Balance.Equal(send.Balance) || info.Representative != genAcc.address {
+		t.Fatalf("unexpected account info: %+v", info)
+	}
+
+	entries, err := client.Pending(ctx, acc.address, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("unexpected amount of pending entries: %d", len(entries))
+	}
+	if entries[0].Hash != send.Hash() || entries[0].Source != genAcc.address || !entries[0].Amount.Equal(amount) {
+		t.Fatalf("unexpected pending entry: %+v", entries[0])
+	}
+}
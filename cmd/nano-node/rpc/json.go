@@ -0,0 +1,328 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+var (
+	errBadBlockJSON = errors.New("bad block json")
+)
+
+// Uint64 is an integer that is encoded as a string, as the reference node
+// does. Plain JSON numbers are accepted when decoding as well.
+type Uint64 uint64
+
+// MarshalJSON implements the json.Marshaler interface.
+func (n Uint64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(n), 10))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (n *Uint64) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), "\"")
+	i, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*n = Uint64(i)
+	return nil
+}
+
+// Bool is a boolean that may be encoded as a string, as the reference node
+// does.
+type Bool bool
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), "\"")
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	*b = Bool(v)
+	return nil
+}
+
+// Raw is a balance that is encoded as a decimal string of raw units.
+type Raw nano.Balance
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (r Raw) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (r *Raw) UnmarshalText(text []byte) error {
+	i, ok := new(big.Int).SetString(string(text), 10)
+	if !ok || i.Sign() < 0 || i.BitLen() > nano.BalanceSize*8 {
+		return fmt.Errorf("bad raw amount: %s", text)
+	}
+
+	bytes := i.Bytes()
+	balanceBytes := make([]byte, nano.BalanceSize)
+	copy(balanceBytes[len(balanceBytes)-len(bytes):], bytes)
+
+	var balance nano.Balance
+	if err := balance.UnmarshalBinary(balanceBytes); err != nil {
+		return err
+	}
+
+	*r = Raw(balance)
+	return nil
+}
+
+// String implements the fmt.Stringer interface.
+func (r Raw) String() string {
+	return nano.Balance(r).BigInt().String()
+}
+
+// orderedMap is a JSON object that keeps its keys in the order they were added.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: map[string]interface{}{}}
+}
+
+// Set sets the value of the given key.
+func (m *orderedMap) Set(key fmt.Stringer, value interface{}) {
+	s := key.String()
+	if _, ok := m.values[s]; !ok {
+		m.keys = append(m.keys, s)
+	}
+	m.values[s] = value
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		keyBytes, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		valueBytes, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(keyBytes)
+		buf.WriteByte(':')
+		buf.Write(valueBytes)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// blockJSON is the JSON representation of a block used by the reference node.
+type blockJSON struct {
+	Type           string          `json:"type"`
+	Account        *nano.Address   `json:"account,omitempty"`
+	Previous       *block.Hash     `json:"previous,omitempty"`
+	Representative *nano.Address   `json:"representative,omitempty"`
+	Balance        string          `json:"balance,omitempty"`
+	Link           *block.Hash     `json:"link,omitempty"`
+	LinkAsAccount  *nano.Address   `json:"link_as_account,omitempty"`
+	Source         *block.Hash     `json:"source,omitempty"`
+	Destination    *nano.Address   `json:"destination,omitempty"`
+	Signature      block.Signature `json:"signature"`
+	Work           block.Work      `json:"work"`
+}
+
+func newBlockJSON(blk block.Block) (*blockJSON, error) {
+	res := blockJSON{Type: block.Name(blk.ID())}
+
+	switch b := blk.(type) {
+	case *block.OpenBlock:
+		res.Source = &b.SourceHash
+		res.Representative = &b.Representative
+		res.Account = &b.Address
+		res.Signature = b.Signature
+		res.Work = b.Work
+	case *block.SendBlock:
+		res.Previous = &b.PreviousHash
+		res.Destination = &b.Destination
+		res.Balance = strings.ToUpper(hex.EncodeToString(b.Balance.Bytes(binary.BigEndian)))
+		res.Signature = b.Signature
+		res.Work = b.Work
+	case *block.ReceiveBlock:
+		res.Previous = &b.PreviousHash
+		res.Source = &b.SourceHash
+		res.Signature = b.Signature
+		res.Work = b.Work
+	case *block.ChangeBlock:
+		res.Previous = &b.PreviousHash
+		res.Representative = &b.Representative
+		res.Signature = b.Signature
+		res.Work = b.Work
+	case *block.StateBlock:
+		linkAsAccount := nano.Address(b.Link)
+		res.Account = &b.Address
+		res.Previous = &b.PreviousHash
+		res.Representative = &b.Representative
+		res.Balance = Raw(b.Balance).String()
+		res.Link = &b.Link
+		res.LinkAsAccount = &linkAsAccount
+		res.Signature = b.Signature
+		res.Work = b.Work
+	default:
+		return nil, block.ErrBadBlockType
+	}
+
+	return &res, nil
+}
+
+// blockContents returns the representation of the given block used by the
+// block_info and blocks_info actions: a JSON object if jsonBlock is set, or a
+// string containing the JSON object otherwise.
+func blockContents(blk block.Block, jsonBlock bool) (interface{}, error) {
+	contents, err := newBlockJSON(blk)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBlock {
+		return contents, nil
+	}
+
+	data, err := json.Marshal(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Block converts the JSON representation back to a block.
+func (b *blockJSON) Block() (block.Block, error) {
+	switch b.Type {
+	case "open":
+		if b.Source == nil || b.Representative == nil || b.Account == nil {
+			return nil, errBadBlockJSON
+		}
+
+		return &block.OpenBlock{
+			SourceHash:     *b.Source,
+			Representative: *b.Representative,
+			Address:        *b.Account,
+			Signature:      b.Signature,
+			Work:           b.Work,
+		}, nil
+	case "send":
+		if b.Previous == nil || b.Destination == nil {
+			return nil, errBadBlockJSON
+		}
+
+		balanceBytes, err := hex.DecodeString(b.Balance)
+		if err != nil {
+			return nil, err
+		}
+		if len(balanceBytes) != nano.BalanceSize {
+			return nil, nano.ErrBadBalanceSize
+		}
+
+		var balance nano.Balance
+		if err := balance.UnmarshalBinary(balanceBytes); err != nil {
+			return nil, err
+		}
+
+		return &block.SendBlock{
+			PreviousHash: *b.Previous,
+			Destination:  *b.Destination,
+			Balance:      balance,
+			Signature:    b.Signature,
+			Work:         b.Work,
+		}, nil
+	case "receive":
+		if b.Previous == nil || b.Source == nil {
+			return nil, errBadBlockJSON
+		}
+
+		return &block.ReceiveBlock{
+			PreviousHash: *b.Previous,
+			SourceHash:   *b.Source,
+			Signature:    b.Signature,
+			Work:         b.Work,
+		}, nil
+	case "change":
+		if b.Previous == nil || b.Representative == nil {
+			return nil, errBadBlockJSON
+		}
+
+		return &block.ChangeBlock{
+			PreviousHash:   *b.Previous,
+			Representative: *b.Representative,
+			Signature:      b.Signature,
+			Work:           b.Work,
+		}, nil
+	case "state":
+		if b.Account == nil || b.Previous == nil || b.Representative == nil {
+			return nil, errBadBlockJSON
+		}
+
+		var balance Raw
+		if err := balance.UnmarshalText([]byte(b.Balance)); err != nil {
+			return nil, err
+		}
+
+		// the link may be given as an account as well
+		var link block.Hash
+		if b.Link != nil {
+			link = *b.Link
+		} else if b.LinkAsAccount != nil {
+			link = block.Hash(*b.LinkAsAccount)
+		} else {
+			return nil, errBadBlockJSON
+		}
+
+		return &block.StateBlock{
+			Address:        *b.Account,
+			PreviousHash:   *b.Previous,
+			Representative: *b.Representative,
+			Balance:        nano.Balance(balance),
+			Link:           link,
+			Signature:      b.Signature,
+			Work:           b.Work,
+		}, nil
+	default:
+		return nil, block.ErrBadBlockType
+	}
+}
+
+// parseBlock parses a block in the format accepted by the process action: a
+// JSON object, or a string containing a JSON object.
+func parseBlock(data json.RawMessage) (block.Block, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		data = json.RawMessage(s)
+	}
+
+	var b blockJSON
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, err
+	}
+
+	return b.Block()
+}
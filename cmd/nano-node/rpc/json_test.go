@@ -0,0 +1,204 @@
+package rpc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+type testKey string
+
+func (k testKey) String() string {
+	return string(k)
+}
+
+func TestRaw(t *testing.T) {
+	tests := []struct {
+		json    string
+		balance nano.Balance
+		ok      bool
+	}{
+		{`"0"`, nano.ZeroBalance, true},
+		{`"1000"`, nano.ParseBalanceInts(0, 1000), true},
+		{`"18446744073709551616"`, nano.ParseBalanceInts(1, 0), true},
+		{`"340282366920938463463374607431768211455"`, nano.ParseBalanceInts(0xffffffffffffffff, 0xffffffffffffffff), true},
+		{`"340282366920938463463374607431768211456"`, nano.ZeroBalance, false},
+		{`"-1"`, nano.ZeroBalance, false},
+		{`"1.5"`, nano.ZeroBalance, false},
+		{`""`, nano.ZeroBalance, false},
+	}
+
+	for _, test := range tests {
+		var raw Raw
+		err := json.Unmarshal([]byte(test.json), &raw)
+		if (err == nil) != test.ok {
+			t.Fatalf("unexpected result decoding %s: %v", test.json, err)
+		}
+		if !test.ok {
+			continue
+		}
+
+		if !nano.Balance(raw).Equal(test.balance) {
+			t.Fatalf("unexpected balance for %s: %s", test.json, raw)
+		}
+		if data, err := json.Marshal(raw); err != nil || string(data) != test.json {
+			t.Fatalf("unexpected encoding of %s: %s (err: %v)", test.json, data, err)
+		}
+	}
+}
+
+func TestUint64(t *testing.T) {
+	tests := []struct {
+		json  string
+		value Uint64
+		ok    bool
+	}{
+		{`"0"`, 0, true},
+		{`"42"`, 42, true},
+		{`42`, 42, true},
+		{`"18446744073709551615"`, 18446744073709551615, true},
+		{`"18446744073709551616"`, 0, false},
+		{`"-1"`, 0, false},
+		{`"abc"`, 0, false},
+	}
+
+	for _, test := range tests {
+		var value Uint64
+		err := json.Unmarshal([]byte(test.json), &value)
+		if (err == nil) != test.ok {
+			t.Fatalf("unexpected result decoding %s: %v", test.json, err)
+		}
+		if test.ok && value != test.value {
+			t.Fatalf("unexpected value for %s: %d", test.json, value)
+		}
+	}
+
+	if data, err := json.Marshal(Uint64(42)); err != nil || string(data) != `"42"` {
+		t.Fatalf("unexpected encoding: %s (err: %v)", data, err)
+	}
+}
+
+func TestBool(t *testing.T) {
+	tests := []struct {
+		json  string
+		value Bool
+		ok    bool
+	}{
+		{`"true"`, true, true},
+		{`"false"`, false, true},
+		{`true`, true, true},
+		{`false`, false, true},
+		{`"1"`, true, true},
+		{`"yes"`, false, false},
+	}
+
+	for _, test := range tests {
+		var value Bool
+		err := json.Unmarshal([]byte(test.json), &value)
+		if (err == nil) != test.ok {
+			t.Fatalf("unexpected result decoding %s: %v", test.json, err)
+		}
+		if test.ok && value != test.value {
+			t.Fatalf("unexpected value for %s: %t", test.json, value)
+		}
+	}
+}
+
+func TestOrderedMap(t *testing.T) {
+	tests := []struct {
+		keys   []string
+		values []interface{}
+		json   string
+	}{
+		{nil, nil, `{}`},
+		{[]string{"b", "a", "c"}, []interface{}{1, "x", true}, `{"b":1,"a":"x","c":true}`},
+		{[]string{"b", "a", "b"}, []interface{}{1, 2, 3}, `{"b":3,"a":2}`},
+		{[]string{`"quoted"`}, []interface{}{Raw(nano.ParseBalanceInts(0, 1))}, `{"\"quoted\"":"1"}`},
+	}
+
+	for _, test := range tests {
+		m := newOrderedMap()
+		for i, key := range test.keys {
+			m.Set(testKey(key), test.values[i])
+		}
+
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.json {
+			t.Fatalf("unexpected encoding: %s != %s", data, test.json)
+		}
+	}
+}
+
+func TestBlockJSON(t *testing.T) {
+	acc := newTestAccount(t)
+	var hash block.Hash
+	copy(hash[:], "some block hash")
+	balance := nano.ParseBalanceInts(1, 1000)
+
+	blocks := []block.Block{
+		&block.OpenBlock{SourceHash: hash, Representative: acc.address, Address: acc.address},
+		&block.SendBlock{PreviousHash: hash, Destination: acc.address, Balance: balance},
+		&block.ReceiveBlock{PreviousHash: hash, SourceHash: hash},
+		&block.ChangeBlock{PreviousHash: hash, Representative: acc.address},
+		&block.StateBlock{Address: acc.address, PreviousHash: hash, Representative: acc.address, Balance: balance, Link: hash},
+	}
+
+	for _, blk := range blocks {
+		contents, err := blockContents(blk, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(contents)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// blocks can be given as an object or as a string containing one
+		str, err := blockContents(blk, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		strData, err := json.Marshal(str)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, data := range [][]byte{data, strData} {
+			res, err := parseBlock(data)
+			if err != nil {
+				t.Fatalf("error parsing %s: %v", data, err)
+			}
+			if res.Hash() != blk.Hash() {
+				t.Fatalf("unexpected block after parsing %s", data)
+			}
+		}
+	}
+
+	// the link of state blocks may be given as an account
+	state := blocks[4].(*block.StateBlock)
+	data := []byte(`{"type":"state","account":"` + acc.address.String() + `","previous":"` + hash.String() +
+		`","representative":"` + acc.address.String() + `","balance":"` + Raw(balance).String() +
+		`","link_as_account":"` + nano.Address(hash).String() + `"}`)
+	if res, err := parseBlock(data); err != nil || res.Hash() != state.Hash() {
+		t.Fatalf("unexpected result parsing a block with link_as_account: %v", err)
+	}
+
+	bad := []string{
+		`{"type":"open"}`,
+		`{"type":"send","previous":"` + hash.String() + `"}`,
+		`{"type":"state","account":"` + acc.address.String() + `"}`,
+		`{"type":"unknown"}`,
+		`"not a block"`,
+		`42`,
+	}
+	for _, data := range bad {
+		if _, err := parseBlock([]byte(data)); err == nil {
+			t.Fatalf("expected an error parsing %s", data)
+		}
+	}
+}
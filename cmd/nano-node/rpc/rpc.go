@@ -0,0 +1,109 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/alexbakker/gonano/nano/node"
+	"github.com/alexbakker/gonano/nano/store"
+)
+
+const (
+	// Version is the version of the RPC protocol reported by the version
+	// action.
+	Version = "1"
+
+	maxRequestSize = 1 << 20
+)
+
+var (
+	ErrUnknownAction   = errors.New("unknown command")
+	ErrAccountNotFound = errors.New("account not found")
+	ErrBlockNotFound   = errors.New("block not found")
+)
+
+type actionFunc func(s *Server, data []byte) (interface{}, error)
+
+var (
+	actions = map[string]actionFunc{
+		"account_balance": (*Server).accountBalance,
+		"account_info":    (*Server).accountInfo,
+		"account_history": (*Server).accountHistory,
+		"block_info":      (*Server).blockInfo,
+		"blocks_info":     (*Server).blocksInfo,
+		"pending":         (*Server).pending,
+		"process":         (*Server).process,
+		"block_count":     (*Server).blockCount,
+		"frontiers":       (*Server).frontiers,
+		"representatives": (*Server).representatives,
+		"peers":           (*Server).peers,
+		"version":         (*Server).version,
+	}
+)
+
+// Server is an HTTP server that answers JSON-RPC requests in the format used by
+// the reference Nano node. Every request is a JSON object with an "action"
+// field, the response is a JSON object as well. Errors are reported in the
+// "error" field of the response.
+type Server struct {
+	ledger *store.Ledger
+	node   *node.Node
+}
+
+type request struct {
+	Action string `json:"action"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// New creates a new RPC server that serves information from the given ledger
+// and node.
+func New(ledger *store.Ledger, node *node.Node) *Server {
+	return &Server{
+		ledger: ledger,
+		node:   node,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	res, err := s.handle(data)
+	if err != nil {
+		res = &errorResponse{Error: err.Error()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		fmt.Printf("error writing rpc response: %s\n", err)
+	}
+}
+
+func (s *Server) handle(data []byte) (interface{}, error) {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	action, ok := actions[req.Action]
+	if !ok {
+		return nil, ErrUnknownAction
+	}
+
+	return action(s, data)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+
+	"github.com/alexbakker/gonano/cmd/nano-node/rpc"
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/store"
+	"github.com/alexbakker/gonano/nano/wallet"
+)
+
+const (
+	// the maximum amount of pending transactions that is fetched per account
+	pendingCount = 1000
+)
+
+// backend is the connection to the node the wallet reads the state of its
+// accounts from and publishes its blocks to. The wallet doesn't run a node of
+// its own, because building blocks on top of a ledger that is still
+// synchronizing could fork the accounts of the wallet.
+type backend struct {
+	client *rpc.Client
+}
+
+// openBackend connects to the node given by the rpc flag.
+func openBackend() *backend {
+	if rpcURL == "" {
+		logger.Fatalf("no node to connect to, use the --rpc flag")
+	}
+
+	return &backend{client: rpc.NewClient(rpcURL)}
+}
+
+// AccountState returns the state of the given account, or nil if the account
+// isn't opened yet.
+func (b *backend) AccountState(ctx context.Context, address nano.Address) (*wallet.AccountState, error) {
+	info, err := b.client.AccountInfo(ctx, address)
+	if err != nil {
+		if err == rpc.ErrAccountNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &wallet.AccountState{
+		Head:           info.Frontier,
+		Representative: info.Representative,
+		Balance:        info.Balance,
+		Epoch:          info.Epoch,
+	}, nil
+}
+
+func (b *backend) Pending(ctx context.Context, address nano.Address) ([]*store.PendingEntry, error) {
+	return b.client.Pending(ctx, address, pendingCount)
+}
+
+func (b *backend) Process(ctx context.Context, blk block.Block) error {
+	_, err := b.client.Process(ctx, blk)
+	return err
+}
+
+func (b *backend) Close() error {
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/wallet"
+	"github.com/spf13/cobra"
+)
+
+var (
+	balanceCmd = &cobra.Command{
+		Use:   "balance [account]...",
+		Short: "Show the balance of the accounts in the wallet",
+		Run:   showBalance,
+	}
+	sendCmd = &cobra.Command{
+		Use:   "send <account> <destination> <amount>",
+		Short: "Send an amount from an account in the wallet",
+		Args:  cobra.ExactArgs(3),
+		Run:   send,
+	}
+	receiveAllCmd = &cobra.Command{
+		Use:   "receive-all [account]...",
+		Short: "Receive all pending transactions of the accounts in the wallet",
+		Run:   receiveAll,
+	}
+	changeRepCmd = &cobra.Command{
+		Use:   "change-rep <account> <representative>",
+		Short: "Change the representative of an account in the wallet",
+		Args:  cobra.ExactArgs(2),
+		Run:   changeRep,
+	}
+
+	unit string
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&unit, "unit", "Mxrb", "unit of amounts (raw, uxrb, mxrb, xrb, kxrb, Mxrb or Gxrb)")
+
+	rootCmd.AddCommand(balanceCmd)
+	rootCmd.AddCommand(sendCmd)
+	rootCmd.AddCommand(receiveAllCmd)
+	rootCmd.AddCommand(changeRepCmd)
+}
+
+// checkUnit makes sure the unit flag is a known unit.
+func checkUnit() {
+	if one, err := nano.ParseBalance("1", unit); err != nil || one.Equal(nano.ZeroBalance) {
+		logger.Fatalf("unknown unit: %s", unit)
+	}
+}
+
+// selectAddresses returns the addresses given as arguments, or all addresses in
+// the wallet if there are none.
+func selectAddresses(w *wallet.Wallet, args []string) []nano.Address {
+	if len(args) == 0 {
+		return w.Addresses()
+	}
+
+	var addresses []nano.Address
+	for _, arg := range args {
+		addresses = append(addresses, walletAccount(w, parseAddress(arg)).Address())
+	}
+	return addresses
+}
+
+func showBalance(cmd *cobra.Command, args []string) {
+	checkUnit()
+	w, _ := loadWallet()
+	addresses := selectAddresses(w, args)
+
+	ctx := newContext()
+	b := openBackend()
+	defer b.Close()
+
+	for _, address := range addresses {
+		balance := nano.ZeroBalance
+		state, err := b.AccountState(ctx, address)
+		if err != nil {
+			b.Close()
+			logger.Fatalf("error obtaining account state of %s: %s", address, err)
+		}
+		if state != nil {
+			balance = state.Balance
+		}
+
+		entries, err := b.Pending(ctx, address)
+		if err != nil {
+			b.Close()
+			logger.Fatalf("error obtaining pending transactions of %s: %s", address, err)
+		}
+
+		pending := nano.ZeroBalance
+		for _, entry := range entries {
+			pending = pending.Add(entry.Amount)
+		}
+
+		fmt.Printf("%s\t%s\t%s pending\n", address, balance.UnitString(unit, nano.BalanceMaxPrecision), pending.UnitString(unit, nano.BalanceMaxPrecision))
+	}
+}
+
+func send(cmd *cobra.Command, args []string) {
+	checkUnit()
+	w, _ := loadWallet()
+	acc := walletAccount(w, parseAddress(args[0]))
+	destination := parseAddress(args[1])
+	amount, err := nano.ParseBalance(args[2], unit)
+	if err != nil {
+		logger.Fatalf("bad amount: %s", err)
+	}
+
+	ctx := newContext()
+	b := openBackend()
+	defer b.Close()
+
+	acc.SetWork(workOptions())
+	publish(ctx, b, acc, func(state *wallet.AccountState) (*block.StateBlock, error) {
+		if state == nil {
+			return nil, wallet.ErrAccountNotOpen
+		}
+		return acc.Send(ctx, state, destination, amount)
+	})
+}
+
+func receiveAll(cmd *cobra.Command, args []string) {
+	w, _ := loadWallet()
+	addresses := selectAddresses(w, args)
+
+	ctx := newContext()
+	b := openBackend()
+	defer b.Close()
+
+	work := workOptions()
+	for _, address := range addresses {
+		entries, err := b.Pending(ctx, address)
+		if err != nil {
+			b.Close()
+			logger.Fatalf("error obtaining pending transactions of %s: %s", address, err)
+		}
+
+		acc := walletAccount(w, address)
+		acc.SetWork(work)
+		for _, entry := range entries {
+			entry := entry
+			publish(ctx, b, acc, func(state *wallet.AccountState) (*block.StateBlock, error) {
+				if state == nil {
+					// accounts represent themselves if there's no default
+					rep := w.Representative()
+					if rep == (nano.Address{}) {
+						rep = address
+					}
+					return acc.Open(ctx, entry.Hash, entry.Amount, rep)
+				}
+				return acc.Receive(ctx, state, entry.Hash, entry.Amount)
+			})
+		}
+	}
+}
+
+func changeRep(cmd *cobra.Command, args []string) {
+	w, _ := loadWallet()
+	acc := walletAccount(w, parseAddress(args[0]))
+	rep := parseAddress(args[1])
+
+	ctx := newContext()
+	b := openBackend()
+	defer b.Close()
+
+	acc.SetWork(workOptions())
+	publish(ctx, b, acc, func(state *wallet.AccountState) (*block.StateBlock, error) {
+		if state == nil {
+			return nil, wallet.ErrAccountNotOpen
+		}
+		return acc.ChangeRep(ctx, state, rep)
+	})
+}
+
+// publish builds a block on top of the current state of the given account and
+// publishes it.
+func publish(ctx context.Context, b *backend, acc *wallet.Account, build func(state *wallet.AccountState) (*block.StateBlock, error)) {
+	state, err := b.AccountState(ctx, acc.Address())
+	if err != nil {
+		b.Close()
+		logger.Fatalf("error obtaining account state of %s: %s", acc.Address(), err)
+	}
+
+	logger.Printf("building block for %s", acc.Address())
+	blk, err := build(state)
+	if err != nil {
+		b.Close()
+		logger.Fatalf("error building block for %s: %s", acc.Address(), err)
+	}
+
+	if err := b.Process(ctx, blk); err != nil {
+		b.Close()
+		logger.Fatalf("error publishing block %s: %s", blk.Hash(), err)
+	}
+
+	logger.Printf("published block %s", blk.Hash())
+}
@@ -1,9 +1,183 @@
 package main
 
-import "syscall"
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"os/user"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/node/proto"
+	"github.com/alexbakker/gonano/nano/store/genesis"
+	"github.com/alexbakker/gonano/nano/wallet"
+	"github.com/alexbakker/gonano/nano/work"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var (
+	rootCmd = &cobra.Command{
+		Use:   "nano-wallet",
+		Short: "Nano wallet",
+	}
+
+	walletPath  string
+	networkName string
+	rpcURL      string
+	workURL     string
+	threads     int
+
+	stdin  = bufio.NewReader(os.Stdin)
+	logger = log.New(os.Stdout, "", log.Ldate|log.Lmicroseconds)
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&walletPath, "wallet", defaultWalletPath(), "path of the wallet file")
+	rootCmd.PersistentFlags().StringVar(&networkName, "network", "live", "network to use (live or beta)")
+	rootCmd.PersistentFlags().StringVar(&rpcURL, "rpc", "", "URL of the RPC interface of the node to use")
+	rootCmd.PersistentFlags().StringVar(&workURL, "work-url", "", "URL of a work server to use instead of generating work locally")
+	rootCmd.PersistentFlags().IntVar(&threads, "threads", 0, "number of threads to generate work with (0 means one per CPU)")
+}
 
 func main() {
 	// set the umask of this process to 077
 	// this ensures all written files are only readable/writable by the current user
 	syscall.Umask(077)
+
+	if err := rootCmd.Execute(); err != nil {
+		logger.Fatalf("error: %s", err.Error())
+	}
+}
+
+func defaultWalletPath() string {
+	usr, err := user.Current()
+	if err != nil {
+		return "wallet.dat"
+	}
+
+	return path.Join(usr.HomeDir, ".config/gonano/wallet", "wallet.dat")
+}
+
+func network() proto.Network {
+	var network proto.Network
+	if err := network.UnmarshalText([]byte(networkName)); err != nil {
+		logger.Fatalf("bad network: %s", err)
+	}
+
+	return network
+}
+
+// newContext returns a context that is canceled when the process receives an
+// interrupt signal, so that work generation can be stopped.
+func newContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigc
+		logger.Println("exit signal caught")
+		cancel()
+	}()
+
+	return ctx
+}
+
+// readPassword asks for a password on the terminal. If stdin is not a
+// terminal, the password is read from the next line of stdin instead.
+func readPassword(prompt string) string {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			logger.Fatalf("error reading password: %s", err)
+		}
+		return strings.TrimRight(line, "\r\n")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		logger.Fatalf("error reading password: %s", err)
+	}
+
+	return string(password)
+}
+
+// newPassword asks for a new password twice.
+func newPassword() string {
+	password := readPassword("New password: ")
+	if readPassword("Repeat password: ") != password {
+		logger.Fatalf("the passwords don't match")
+	}
+
+	return password
+}
+
+// loadWallet asks for the password of the wallet file and loads it.
+func loadWallet() (*wallet.Wallet, string) {
+	password := readPassword("Password: ")
+	w, err := wallet.Load(walletPath, password)
+	if err != nil {
+		logger.Fatalf("error loading wallet: %s", err)
+	}
+
+	return w, password
+}
+
+// createWallet saves a new wallet, which must not overwrite an existing one.
+func createWallet(w *wallet.Wallet) {
+	if _, err := os.Stat(walletPath); err == nil {
+		logger.Fatalf("a wallet already exists at %s", walletPath)
+	}
+
+	password := newPassword()
+	if err := os.MkdirAll(path.Dir(walletPath), 0700); err != nil {
+		logger.Fatalf("error creating wallet directory: %s", err)
+	}
+	saveWallet(w, password)
+}
+
+func saveWallet(w *wallet.Wallet, password string) {
+	if err := w.Save(walletPath, password); err != nil {
+		logger.Fatalf("error saving wallet: %s", err)
+	}
+}
+
+// workOptions returns the options to attach work to blocks with, based on
+// the work flags and the work thresholds of the network.
+func workOptions() *wallet.WorkOptions {
+	gen, err := genesis.Get(network())
+	if err != nil {
+		logger.Fatalf("error obtaining genesis info: %s", err)
+	}
+
+	var provider block.WorkProvider
+	if workURL != "" {
+		provider = work.NewClient(workURL)
+	} else {
+		provider = block.NewLocalWorkProvider(threads)
+	}
+
+	return &wallet.WorkOptions{
+		Provider:   provider,
+		Thresholds: gen.WorkThresholds,
+	}
+}
+
+func parseAddress(s string) nano.Address {
+	address, err := nano.ParseAddress(s)
+	if err != nil {
+		logger.Fatalf("bad address %s: %s", s, err)
+	}
+
+	return address
 }
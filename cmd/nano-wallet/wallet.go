@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/wallet"
+	"github.com/spf13/cobra"
+)
+
+var (
+	createCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Create a new wallet with a random seed",
+		Args:  cobra.NoArgs,
+		Run:   create,
+	}
+	restoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Restore a wallet from a seed or a BIP39 mnemonic",
+		Args:  cobra.NoArgs,
+		Run:   restore,
+	}
+	exportSeedCmd = &cobra.Command{
+		Use:   "export-seed",
+		Short: "Print the seed or the BIP39 mnemonic of the wallet",
+		Args:  cobra.NoArgs,
+		Run:   exportSeed,
+	}
+	accountsCmd = &cobra.Command{
+		Use:   "accounts",
+		Short: "List the accounts in the wallet",
+		Args:  cobra.NoArgs,
+		Run:   listAccounts,
+	}
+	newAccountCmd = &cobra.Command{
+		Use:   "new-account",
+		Short: "Derive the next account from the seed",
+		Args:  cobra.NoArgs,
+		Run:   newAccount,
+	}
+
+	walletRep       string
+	createBIP39     bool
+	restoreSeed     string
+	restoreMnemonic string
+	restoreIndex    uint32
+	accountLabel    string
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{createCmd, restoreCmd} {
+		cmd.Flags().StringVar(&walletRep, "rep", "", "default representative of new accounts (an account represents itself if empty)")
+	}
+	createCmd.Flags().BoolVar(&createBIP39, "bip39", false, "generate a BIP39 mnemonic and derive the accounts with BIP44")
+	restoreCmd.Flags().StringVar(&restoreSeed, "seed", "", "hex-encoded seed to restore")
+	restoreCmd.Flags().StringVar(&restoreMnemonic, "mnemonic", "", "BIP39 mnemonic to restore")
+	restoreCmd.Flags().Uint32Var(&restoreIndex, "index", 0, "index of the last account to derive from the seed")
+	newAccountCmd.Flags().StringVar(&accountLabel, "label", "", "label of the new account")
+
+	rootCmd.AddCommand(createCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(exportSeedCmd)
+	rootCmd.AddCommand(accountsCmd)
+	rootCmd.AddCommand(newAccountCmd)
+}
+
+func create(cmd *cobra.Command, args []string) {
+	if !createBIP39 {
+		w, err := wallet.Generate()
+		if err != nil {
+			logger.Fatalf("error generating wallet: %s", err)
+		}
+
+		initWallet(w)
+		return
+	}
+
+	mnemonic, err := wallet.GenerateMnemonic(256)
+	if err != nil {
+		logger.Fatalf("error generating mnemonic: %s", err)
+	}
+
+	w, err := wallet.NewFromMnemonic(mnemonic, readPassword("BIP39 passphrase (optional): "), 0)
+	if err != nil {
+		logger.Fatalf("error generating wallet: %s", err)
+	}
+
+	initWallet(w)
+	fmt.Printf("mnemonic: %s\n", mnemonic)
+}
+
+func restore(cmd *cobra.Command, args []string) {
+	if (restoreSeed == "") == (restoreMnemonic == "") {
+		logger.Fatalf("either a seed or a mnemonic is required")
+	}
+
+	var w *wallet.Wallet
+	if restoreSeed != "" {
+		seed, err := wallet.ParseSeed(restoreSeed)
+		if err != nil {
+			logger.Fatalf("error parsing seed: %s", err)
+		}
+
+		if w, err = wallet.New(seed, restoreIndex); err != nil {
+			logger.Fatalf("error restoring wallet: %s", err)
+		}
+	} else {
+		if err := wallet.ValidateMnemonic(restoreMnemonic); err != nil {
+			logger.Fatalf("error parsing mnemonic: %s", err)
+		}
+
+		var err error
+		if w, err = wallet.NewFromMnemonic(restoreMnemonic, readPassword("BIP39 passphrase (optional): "), restoreIndex); err != nil {
+			logger.Fatalf("error restoring wallet: %s", err)
+		}
+	}
+
+	initWallet(w)
+}
+
+// initWallet sets the default representative of a new wallet and saves it.
+func initWallet(w *wallet.Wallet) {
+	if walletRep != "" {
+		w.SetRepresentative(parseAddress(walletRep))
+	}
+
+	createWallet(w)
+	logger.Printf("created wallet at %s", walletPath)
+	printAccounts(w)
+}
+
+func exportSeed(cmd *cobra.Command, args []string) {
+	w, _ := loadWallet()
+	if mnemonic, err := w.Mnemonic(); err == nil {
+		fmt.Println(mnemonic)
+		return
+	}
+
+	seed, err := w.Seed()
+	if err != nil {
+		logger.Fatalf("error exporting seed: %s", err)
+	}
+
+	fmt.Println(seed)
+}
+
+func listAccounts(cmd *cobra.Command, args []string) {
+	w, _ := loadWallet()
+	printAccounts(w)
+}
+
+func newAccount(cmd *cobra.Command, args []string) {
+	w, password := loadWallet()
+	acc, err := w.NewAccount()
+	if err != nil {
+		logger.Fatalf("error deriving account: %s", err)
+	}
+	if err := w.SetLabel(acc.Address(), accountLabel); err != nil {
+		logger.Fatalf("error setting label: %s", err)
+	}
+
+	saveWallet(w, password)
+	fmt.Println(acc.Address())
+}
+
+// printAccounts prints the addresses in the wallet along with their labels.
+// Accounts derived from the seed are prefixed with their index.
+func printAccounts(w *wallet.Wallet) {
+	for i, address := range w.Addresses() {
+		index := "-"
+		if uint32(i) <= w.Index() {
+			index = fmt.Sprint(i)
+		}
+
+		fmt.Printf("%s\t%s\t%s\n", index, address, w.Label(address))
+	}
+}
+
+// walletAccount returns the account of the given address in the wallet.
+func walletAccount(w *wallet.Wallet, address nano.Address) *wallet.Account {
+	acc := w.Account(address)
+	if acc == nil {
+		logger.Fatalf("account %s is not in the wallet", address)
+	}
+
+	return acc
+}
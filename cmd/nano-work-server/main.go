@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/store/genesis"
+	"github.com/alexbakker/gonano/nano/work"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rootCmd = &cobra.Command{
+		Use:   "nano-work-server",
+		Short: "A work server for Nano",
+		Args:  cobra.NoArgs,
+		Run:   startServer,
+	}
+
+	addr       string
+	threads    int
+	difficulty string
+
+	logger = log.New(os.Stdout, "", log.Ldate|log.Lmicroseconds)
+)
+
+// provider logs the work values that are generated by the local work
+// generator.
+type provider struct {
+	*block.LocalWorkProvider
+}
+
+func init() {
+	rootCmd.Flags().StringVar(&addr, "addr", "[::1]:7076", "address to listen on for work requests")
+	rootCmd.Flags().IntVar(&threads, "threads", 0, "number of threads to use (0 means one per CPU)")
+	rootCmd.Flags().StringVar(&difficulty, "difficulty", work.Difficulty(genesis.Live.WorkThresholds.Base).String(), "difficulty of requests that don't specify one")
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		logger.Fatalf("error: %s", err.Error())
+	}
+}
+
+func startServer(cmd *cobra.Command, args []string) {
+	var base work.Difficulty
+	if err := base.UnmarshalText([]byte(difficulty)); err != nil {
+		logger.Fatalf("bad difficulty: %s", err)
+	}
+
+	local := block.NewLocalWorkProvider(threads)
+	local.Generator.ReportInterval = 10 * time.Second
+	local.Generator.Report = func(rate float64) {
+		logger.Printf("searching at %.0f attempts per second", rate)
+	}
+
+	server := work.NewServer(&provider{local}, uint64(base))
+	logger.Printf("starting work server at %s", addr)
+	logger.Fatalf("error running work server: %s", http.ListenAndServe(addr, server))
+}
+
+// GenerateWork implements the block.WorkProvider interface.
+func (p *provider) GenerateWork(ctx context.Context, root block.Hash, difficulty uint64) (block.Work, error) {
+	start := time.Now()
+	res, err := p.LocalWorkProvider.GenerateWork(ctx, root, difficulty)
+	if err != nil {
+		logger.Printf("work generation for %s failed after %s: %s", root, time.Since(start), err)
+		return res, err
+	}
+
+	logger.Printf("generated work for %s in %s", root, time.Since(start))
+	return res, nil
+}
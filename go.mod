@@ -8,4 +8,5 @@ require (
 	github.com/shopspring/decimal v0.0.0-20191130220710-360f2bc03045
 	github.com/spf13/cobra v0.0.5
 	golang.org/x/crypto v0.0.0-20191227163750-53104e6ec876
+	golang.org/x/text v0.3.2
 )
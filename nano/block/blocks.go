@@ -20,6 +20,11 @@ const (
 	idBlockState
 )
 
+const (
+	// IDNotABlock is the block type that marks the end of a stream of blocks.
+	IDNotABlock = idBlockNotABlock
+)
+
 var (
 	ErrBadBlockType = errors.New("bad block type")
 	ErrNotABlock    = errors.New("block type is not_a_block")
@@ -50,6 +55,7 @@ type Block interface {
 	encoding.BinaryUnmarshaler
 	Hash() Hash
 	Root() Hash
+	Previous() Hash
 	Size() int
 	ID() byte
 	Valid(threshold uint64) bool
@@ -216,6 +222,10 @@ func (b *OpenBlock) Root() Hash {
 	return b.SourceHash
 }
 
+func (b *OpenBlock) Previous() Hash {
+	return Hash{}
+}
+
 func (b *OpenBlock) Size() int {
 	return blockSizeOpen
 }
@@ -293,6 +303,10 @@ func (b *SendBlock) Root() Hash {
 	return b.PreviousHash
 }
 
+func (b *SendBlock) Previous() Hash {
+	return b.PreviousHash
+}
+
 func (b *SendBlock) Size() int {
 	return blockSizeSend
 }
@@ -358,6 +372,10 @@ func (b *ReceiveBlock) Root() Hash {
 	return b.PreviousHash
 }
 
+func (b *ReceiveBlock) Previous() Hash {
+	return b.PreviousHash
+}
+
 func (b *ReceiveBlock) Size() int {
 	return blockSizeReceive
 }
@@ -423,6 +441,10 @@ func (b *ChangeBlock) Root() Hash {
 	return b.PreviousHash
 }
 
+func (b *ChangeBlock) Previous() Hash {
+	return b.PreviousHash
+}
+
 func (b *ChangeBlock) Size() int {
 	return blockSizeChange
 }
@@ -522,6 +544,10 @@ func (b *StateBlock) Root() Hash {
 	return b.Link
 }
 
+func (b *StateBlock) Previous() Hash {
+	return b.PreviousHash
+}
+
 func (b *StateBlock) Size() int {
 	return blockSizeState
 }
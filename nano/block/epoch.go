@@ -0,0 +1,20 @@
+package block
+
+import "fmt"
+
+// Epoch is the version of an account chain. Accounts are upgraded to the next
+// epoch with an epoch block: a state block that doesn't change the balance or
+// the representative of the account, signed by the signer of that epoch
+// instead of the owner of the account.
+type Epoch byte
+
+const (
+	Epoch0 Epoch = iota
+	Epoch1
+	Epoch2
+)
+
+// String implements the fmt.Stringer interface.
+func (e Epoch) String() string {
+	return fmt.Sprintf("epoch_%d", e)
+}
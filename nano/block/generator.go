@@ -0,0 +1,130 @@
+package block
+
+import (
+	"context"
+	"encoding/binary"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexbakker/gonano/nano/crypto/random"
+)
+
+const (
+	// the amount of work values a goroutine tries before it checks whether the
+	// search is over
+	workBatchSize = 1 << 12
+)
+
+// WorkGenerator searches for work values on several goroutines at once. Every
+// goroutine starts at a random point in the nonce space.
+type WorkGenerator struct {
+	// Threads is the amount of goroutines to search on. If it's zero, one
+	// goroutine per CPU is used.
+	Threads int
+	// Report is called every ReportInterval during a search with the amount
+	// of work values that were tried per second. It's optional.
+	Report func(rate float64)
+	// ReportInterval is the time between calls to Report.
+	ReportInterval time.Duration
+}
+
+// NewWorkGenerator returns a WorkGenerator that searches on the given amount of
+// goroutines. If threads is zero, one goroutine per CPU is used.
+func NewWorkGenerator(threads int) *WorkGenerator {
+	return &WorkGenerator{
+		Threads:        threads,
+		ReportInterval: time.Second,
+	}
+}
+
+// Generate returns the first work value that is found for the given root with
+// a difficulty of at least threshold. If ctx is done before that, its error is
+// returned instead.
+func (g *WorkGenerator) Generate(ctx context.Context, root Hash, threshold uint64) (Work, error) {
+	threads := g.Threads
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the channel is big enough to never block the goroutines that find a
+	// work value after the first one
+	results := make(chan Work, threads)
+	var attempts uint64
+	var wg sync.WaitGroup
+
+	for i := 0; i < threads; i++ {
+		var start [WorkSize]byte
+		if err := random.Bytes(start[:]); err != nil {
+			return 0, err
+		}
+
+		wg.Add(1)
+		go func(start Work) {
+			defer wg.Done()
+			g.search(ctx, NewWorker(start, root, threshold), &attempts, results)
+		}(Work(binary.LittleEndian.Uint64(start[:])))
+	}
+
+	if g.Report != nil && g.ReportInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.report(ctx, &attempts)
+		}()
+	}
+
+	var work Work
+	var err error
+	select {
+	case work = <-results:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	// stop the other goroutines before returning
+	cancel()
+	wg.Wait()
+	return work, err
+}
+
+func (g *WorkGenerator) search(ctx context.Context, worker *Worker, attempts *uint64, results chan<- Work) {
+	for {
+		for i := 0; i < workBatchSize; i++ {
+			if worker.Valid() {
+				results <- worker.work
+				return
+			}
+			worker.work++
+		}
+		atomic.AddUint64(attempts, workBatchSize)
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+	}
+}
+
+func (g *WorkGenerator) report(ctx context.Context, attempts *uint64) {
+	ticker := time.NewTicker(g.ReportInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	var lastAttempts uint64
+	for {
+		select {
+		case now := <-ticker.C:
+			current := atomic.LoadUint64(attempts)
+			g.Report(float64(current-lastAttempts) / now.Sub(last).Seconds())
+			last, lastAttempts = now, current
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package block
+
+import (
+	"context"
+)
+
+// WorkProvider generates work values for blocks.
+type WorkProvider interface {
+	// GenerateWork returns a work value for the given root with a difficulty
+	// of at least the given one. If ctx is done before a work value is
+	// found, the search is canceled and the error of ctx is returned.
+	GenerateWork(ctx context.Context, root Hash, difficulty uint64) (Work, error)
+}
+
+// LocalWorkProvider is a WorkProvider that generates work values in this
+// process.
+type LocalWorkProvider struct {
+	Generator *WorkGenerator
+}
+
+// NewLocalWorkProvider returns a LocalWorkProvider that searches for work
+// values on the given amount of goroutines. If threads is zero, one goroutine
+// per CPU is used.
+func NewLocalWorkProvider(threads int) *LocalWorkProvider {
+	return &LocalWorkProvider{Generator: NewWorkGenerator(threads)}
+}
+
+// GenerateWork implements the WorkProvider interface.
+func (p *LocalWorkProvider) GenerateWork(ctx context.Context, root Hash, difficulty uint64) (Work, error) {
+	return p.Generator.Generate(ctx, root, difficulty)
+}
@@ -0,0 +1,35 @@
+package block
+
+// Subtype describes what a block does to its account chain. For legacy blocks
+// this follows from the block type, for state blocks it follows from the
+// difference with the previous block.
+type Subtype byte
+
+const (
+	SubtypeInvalid Subtype = iota
+	SubtypeSend
+	SubtypeReceive
+	SubtypeOpen
+	SubtypeChange
+	SubtypeEpoch
+)
+
+var (
+	subtypeNames = map[Subtype]string{
+		SubtypeInvalid: "invalid",
+		SubtypeSend:    "send",
+		SubtypeReceive: "receive",
+		SubtypeOpen:    "open",
+		SubtypeChange:  "change",
+		SubtypeEpoch:   "epoch",
+	}
+)
+
+// String implements the fmt.Stringer interface.
+func (s Subtype) String() string {
+	if name, ok := subtypeNames[s]; ok {
+		return name
+	}
+
+	return subtypeNames[SubtypeInvalid]
+}
@@ -0,0 +1,57 @@
+package block
+
+// WorkThresholds decides the work threshold of a block, based on its subtype
+// and the epoch of its account after the block.
+type WorkThresholds struct {
+	// Base is the threshold for legacy blocks and for state blocks of
+	// accounts in epochs that don't have their own thresholds.
+	Base uint64
+	// Epochs holds the thresholds of the epochs that changed them. Later
+	// epochs inherit the thresholds of the epoch before them.
+	Epochs map[Epoch]EpochThresholds
+}
+
+// EpochThresholds holds the work thresholds of an epoch.
+type EpochThresholds struct {
+	// Send is the threshold for send and change blocks.
+	Send uint64
+	// Receive is the threshold for receive, open and epoch blocks.
+	Receive uint64
+}
+
+// Threshold returns the work threshold for blocks of the given subtype on
+// accounts in the given epoch. For epoch blocks, that's the epoch the account
+// is upgraded to. Legacy blocks use the thresholds of the first epoch.
+func (t *WorkThresholds) Threshold(epoch Epoch, subtype Subtype) uint64 {
+	for e := int(epoch); e >= int(Epoch0); e-- {
+		thresholds, ok := t.Epochs[Epoch(e)]
+		if !ok {
+			continue
+		}
+
+		switch subtype {
+		case SubtypeSend, SubtypeChange:
+			return thresholds.Send
+		default:
+			return thresholds.Receive
+		}
+	}
+
+	return t.Base
+}
+
+// Min returns the lowest threshold of any block. Work values below it are
+// never valid.
+func (t *WorkThresholds) Min() uint64 {
+	min := t.Base
+	for _, thresholds := range t.Epochs {
+		if thresholds.Send < min {
+			min = thresholds.Send
+		}
+		if thresholds.Receive < min {
+			min = thresholds.Receive
+		}
+	}
+
+	return min
+}
@@ -14,6 +14,21 @@ type Vote struct {
 	Block     Block
 }
 
+// Hash returns the hash of this vote. This is what the representative signs.
+func (v *Vote) Hash() Hash {
+	var sequence [8]byte
+	binary.LittleEndian.PutUint64(sequence[:], v.Sequence)
+
+	hash := v.Block.Hash()
+	return hashBytes(hash[:], sequence[:])
+}
+
+// Verify reports whether the signature of this vote is valid.
+func (v *Vote) Verify() bool {
+	hash := v.Hash()
+	return v.Address.Verify(hash[:], v.Signature[:])
+}
+
 // MarshalBinary implements the encoding.BinaryMarshaler interface.
 func (v *Vote) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
@@ -0,0 +1,37 @@
+package block
+
+import (
+	"testing"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/crypto/ed25519"
+)
+
+func TestVoteSignature(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vote := Vote{Sequence: 1, Block: sendBlock}
+	copy(vote.Address[:], pubKey)
+
+	hash := vote.Hash()
+	copy(vote.Signature[:], ed25519.Sign(privKey, hash[:]))
+	if !vote.Verify() {
+		t.Fatal("vote signature not valid")
+	}
+
+	// the sequence number is part of the signed data
+	vote.Sequence++
+	if vote.Verify() {
+		t.Fatal("vote signature valid after changing the sequence")
+	}
+
+	var addr nano.Address
+	vote.Sequence--
+	vote.Address = addr
+	if vote.Verify() {
+		t.Fatal("vote signature valid for a different representative")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"hash"
+	"math"
 
 	"golang.org/x/crypto/blake2b"
 )
@@ -20,12 +21,44 @@ type Worker struct {
 	root      *Hash
 	work      Work
 	hash      hash.Hash
+	sum       [WorkSize]byte
 }
 
 func (w Work) Valid(root Hash, threshold uint64) bool {
 	return NewWorker(w, root, threshold).Valid()
 }
 
+// Difficulty returns the difficulty of the work value for the given root. The
+// work value is valid if its difficulty is at least the threshold.
+func (w Work) Difficulty(root Hash) uint64 {
+	return NewWorker(w, root, 0).Difficulty()
+}
+
+// DifficultyToMultiplier returns how many times harder it is to find a work
+// value with the given difficulty than one with the base difficulty.
+func DifficultyToMultiplier(difficulty uint64, base uint64) float64 {
+	// the amount of work is inversely proportional to 2^64 - difficulty
+	return float64(-base) / float64(-difficulty)
+}
+
+// MultiplierToDifficulty returns the difficulty that is the given amount of
+// times harder to reach than the base difficulty. The multiplier must be
+// positive.
+func MultiplierToDifficulty(multiplier float64, base uint64) uint64 {
+	if multiplier == 1 {
+		return base
+	}
+
+	difficulty := float64(-base) / multiplier
+	switch {
+	case difficulty >= math.MaxUint64:
+		return 0
+	case difficulty < 1:
+		return math.MaxUint64
+	}
+	return -uint64(difficulty)
+}
+
 // MarshalText implements the encoding.TextMarshaler interface.
 func (w Work) MarshalText() ([]byte, error) {
 	return []byte(w.String()), nil
@@ -70,6 +103,11 @@ func NewWorker(work Work, root Hash, threshold uint64) *Worker {
 }
 
 func (w *Worker) Valid() bool {
+	return w.Difficulty() >= w.Threshold
+}
+
+// Difficulty returns the difficulty of the current work value.
+func (w *Worker) Difficulty() uint64 {
 	var workBytes [WorkSize]byte
 	binary.LittleEndian.PutUint64(workBytes[:], uint64(w.work))
 
@@ -77,11 +115,13 @@ func (w *Worker) Valid() bool {
 	w.hash.Write(workBytes[:])
 	w.hash.Write(w.root[:])
 
-	sum := w.hash.Sum(nil)
-	value := binary.LittleEndian.Uint64(sum)
-	return value >= w.Threshold
+	sum := w.hash.Sum(w.sum[:0])
+	return binary.LittleEndian.Uint64(sum)
 }
 
+// Generate searches for a valid work value, starting at the current one. It
+// doesn't return until it finds one, see WorkGenerator for a parallel search
+// that can be canceled.
 func (w *Worker) Generate() Work {
 	for {
 		if w.Valid() {
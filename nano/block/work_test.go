@@ -1,8 +1,10 @@
 package block
 
 import (
+	"context"
 	"encoding/hex"
 	"testing"
+	"time"
 )
 
 func TestBlockWork(t *testing.T) {
@@ -23,6 +25,76 @@ func TestBlockWork(t *testing.T) {
 	worker.Generate()
 }
 
+func TestWorkDifficulty(t *testing.T) {
+	work := Work(0xc2c306caf73b836f)
+	hash := mustDecodeHash(t, "6529c605d4016f486b60861c49ddad128d77642e748b3fe13be411f00ba0918b")
+
+	difficulty := work.Difficulty(hash)
+	if !work.Valid(hash, difficulty) || work.Valid(hash, difficulty+1) {
+		t.Fatalf("difficulty %x doesn't match the threshold", difficulty)
+	}
+}
+
+func TestWorkMultiplier(t *testing.T) {
+	base := uint64(0xffffffc000000000)
+	cases := []struct {
+		difficulty uint64
+		multiplier float64
+	}{
+		{base, 1},
+		{0xfffffff800000000, 8},
+		{0xfffffe0000000000, 0.125},
+	}
+
+	for _, c := range cases {
+		if multiplier := DifficultyToMultiplier(c.difficulty, base); multiplier != c.multiplier {
+			t.Fatalf("unexpected multiplier for %x: %f != %f", c.difficulty, multiplier, c.multiplier)
+		}
+		if difficulty := MultiplierToDifficulty(c.multiplier, base); difficulty != c.difficulty {
+			t.Fatalf("unexpected difficulty for %f: %x != %x", c.multiplier, difficulty, c.difficulty)
+		}
+	}
+
+	if difficulty := MultiplierToDifficulty(1.0/(1<<30), base); difficulty != 0 {
+		t.Fatalf("unexpected difficulty for a tiny multiplier: %x", difficulty)
+	}
+}
+
+func TestWorkThresholds(t *testing.T) {
+	thresholds := WorkThresholds{
+		Base: 0xffffffc000000000,
+		Epochs: map[Epoch]EpochThresholds{
+			Epoch2: {Send: 0xfffffff800000000, Receive: 0xfffffe0000000000},
+		},
+	}
+
+	cases := []struct {
+		epoch     Epoch
+		subtype   Subtype
+		threshold uint64
+	}{
+		{Epoch0, SubtypeSend, 0xffffffc000000000},
+		{Epoch1, SubtypeReceive, 0xffffffc000000000},
+		{Epoch2, SubtypeSend, 0xfffffff800000000},
+		{Epoch2, SubtypeChange, 0xfffffff800000000},
+		{Epoch2, SubtypeReceive, 0xfffffe0000000000},
+		{Epoch2, SubtypeOpen, 0xfffffe0000000000},
+		{Epoch2, SubtypeEpoch, 0xfffffe0000000000},
+		// later epochs inherit the thresholds
+		{Epoch2 + 1, SubtypeSend, 0xfffffff800000000},
+	}
+
+	for _, c := range cases {
+		if threshold := thresholds.Threshold(c.epoch, c.subtype); threshold != c.threshold {
+			t.Fatalf("unexpected threshold for %s blocks in %s: %x != %x", c.subtype, c.epoch, threshold, c.threshold)
+		}
+	}
+
+	if min := thresholds.Min(); min != 0xfffffe0000000000 {
+		t.Fatalf("unexpected minimum threshold: %x", min)
+	}
+}
+
 func mustDecodeHash(t *testing.T, s string) Hash {
 	var hash Hash
 	bytes, err := hex.DecodeString(s)
@@ -32,3 +104,37 @@ func mustDecodeHash(t *testing.T, s string) Hash {
 	copy(hash[:], bytes)
 	return hash
 }
+
+func TestWorkGenerator(t *testing.T) {
+	hash := mustDecodeHash(t, "6529c605d4016f486b60861c49ddad128d77642e748b3fe13be411f00ba0918b")
+	threshold := uint64(0xfff0000000000000)
+
+	gen := NewWorkGenerator(4)
+	work, err := gen.Generate(context.Background(), hash, threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !work.Valid(hash, threshold) {
+		t.Fatalf("work not valid: %s", work)
+	}
+
+	// no work value meets this threshold, so the search only ends when the
+	// context is canceled
+	reported := make(chan float64, 1)
+	gen.ReportInterval = 10 * time.Millisecond
+	gen.Report = func(rate float64) {
+		select {
+		case reported <- rate:
+		default:
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-reported
+		cancel()
+	}()
+	if _, err := gen.Generate(ctx, hash, ^uint64(0)); err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package node
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/store"
+)
+
+const (
+	electionTimeout          = time.Minute * 5
+	electionAnnounceInterval = time.Second * 16
+	onlineRepTimeout         = time.Minute * 5
+	electionMaxBlocks        = 10
+)
+
+var (
+	errBadVoteSignature = errors.New("bad vote signature")
+)
+
+// Election keeps track of the votes for a set of competing blocks that share
+// the same root.
+type Election struct {
+	Root    block.Hash
+	blocks  map[block.Hash]block.Block
+	votes   map[nano.Address]*block.Vote
+	started time.Time
+}
+
+// onlineRep keeps track of when we last received a vote from a representative
+// and what its voting weight was at that time.
+type onlineRep struct {
+	last   time.Time
+	weight nano.Balance
+}
+
+// Elections keeps track of all active elections. A block is confirmed once the
+// voting weight of the representatives that voted for it crosses the quorum.
+// The quorum is a percentage of the online voting weight: the total weight of
+// the representatives we've received votes from recently.
+type Elections struct {
+	mutex     sync.Mutex
+	ledger    *store.Ledger
+	quorum    int
+	minWeight nano.Balance
+	elections map[block.Hash]*Election
+	online    map[nano.Address]onlineRep
+}
+
+// NewElections creates a new list of active elections. The quorum is a
+// percentage of the online voting weight. The online voting weight is never
+// considered to be lower than minWeight.
+func NewElections(ledger *store.Ledger, quorum int, minWeight nano.Balance) *Elections {
+	return &Elections{
+		ledger:    ledger,
+		quorum:    quorum,
+		minWeight: minWeight,
+		elections: map[block.Hash]*Election{},
+		online:    map[nano.Address]onlineRep{},
+	}
+}
+
+// Start starts an election for the root of the given block. If an election
+// for that root is already active, the block is added to it as a candidate.
+func (e *Elections) Start(blk block.Block) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.start(blk)
+}
+
+// Vote processes the given vote. Votes of representatives without any voting
+// weight are ignored. Votes for blocks that don't have an active election are
+// only taken into account if the block is in our ledger and it hasn't been
+// confirmed yet. Votes for blocks that aren't a candidate of the election yet
+// are only taken into account if the work and signature of the block are
+// valid. If the vote caused the election to be decided, the winning block is
+// returned.
+func (e *Elections) Vote(vote *block.Vote) (block.Block, error) {
+	if !vote.Verify() {
+		return nil, errBadVoteSignature
+	}
+
+	// look up the weight before acquiring the lock, so that the ledger is
+	// only accessed once per vote
+	weight, err := e.ledger.GetRepresentation(vote.Address)
+	if err != nil {
+		return nil, err
+	}
+	if weight.Equal(nano.ZeroBalance) {
+		return nil, nil
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	hash := vote.Block.Hash()
+	election, ok := e.elections[store.ForkRoot(vote.Block)]
+	if ok {
+		if _, ok := election.blocks[hash]; !ok {
+			if len(election.blocks) >= electionMaxBlocks {
+				return nil, nil
+			}
+			if err := e.ledger.VerifyBlock(vote.Block); err != nil {
+				return nil, nil
+			}
+		}
+	} else {
+		if _, err := e.ledger.GetBlock(hash); err != nil {
+			if err == store.ErrNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		confirmed, err := e.ledger.Confirmed(hash)
+		if err != nil {
+			return nil, err
+		}
+		if confirmed {
+			return nil, nil
+		}
+
+		election = e.start(vote.Block)
+	}
+
+	e.online[vote.Address] = onlineRep{last: time.Now(), weight: weight}
+
+	// only keep the most recent vote of every representative
+	if last, ok := election.votes[vote.Address]; ok && last.Sequence >= vote.Sequence {
+		return nil, nil
+	}
+	election.votes[vote.Address] = vote
+
+	if _, ok := election.blocks[hash]; !ok {
+		election.blocks[hash] = vote.Block
+	}
+
+	return e.tally(election)
+}
+
+// Active removes any expired elections and returns the candidate blocks of the
+// remaining ones.
+func (e *Elections) Active() []block.Block {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var blocks []block.Block
+	for root, election := range e.elections {
+		if time.Since(election.started) > electionTimeout {
+			delete(e.elections, root)
+			continue
+		}
+
+		for _, blk := range election.blocks {
+			blocks = append(blocks, blk)
+		}
+	}
+
+	return blocks
+}
+
+// OnlineWeight returns the total voting weight of the representatives that
+// voted recently.
+func (e *Elections) OnlineWeight() nano.Balance {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.onlineWeight()
+}
+
+func (e *Elections) start(blk block.Block) *Election {
+	root := store.ForkRoot(blk)
+
+	election, ok := e.elections[root]
+	if !ok {
+		election = &Election{
+			Root:    root,
+			blocks:  map[block.Hash]block.Block{},
+			votes:   map[nano.Address]*block.Vote{},
+			started: time.Now(),
+		}
+		e.elections[root] = election
+	}
+
+	election.blocks[blk.Hash()] = blk
+	return election
+}
+
+// onlineWeight returns the sum of the weights the online representatives had
+// when we last received a vote from them.
+func (e *Elections) onlineWeight() nano.Balance {
+	weight := nano.ZeroBalance
+
+	for address, rep := range e.online {
+		if time.Since(rep.last) > onlineRepTimeout {
+			delete(e.online, address)
+			continue
+		}
+
+		weight = weight.Add(rep.weight)
+	}
+
+	if weight.Compare(e.minWeight) == nano.BalanceCompSmaller {
+		return e.minWeight
+	}
+
+	return weight
+}
+
+// tally checks whether one of the blocks in the given election has reached
+// quorum. If so, the ledger is updated to contain the winning block. Only once
+// the winner is in the ledger, the election is removed from the list and the
+// block is marked as confirmed.
+func (e *Elections) tally(election *Election) (block.Block, error) {
+	votes := make(map[nano.Address]block.Hash, len(election.votes))
+	for rep, vote := range election.votes {
+		votes[rep] = vote.Block.Hash()
+	}
+
+	tally, err := e.ledger.Tally(votes)
+	if err != nil {
+		return nil, err
+	}
+
+	var winner block.Hash
+	weight := nano.ZeroBalance
+	for hash, hashWeight := range tally {
+		if hashWeight.Compare(weight) == nano.BalanceCompBigger {
+			winner = hash
+			weight = hashWeight
+		}
+	}
+
+	if winner.IsZero() {
+		return nil, nil
+	}
+
+	online := e.onlineWeight()
+
+	// weight * 100 >= online * quorum
+	lhs := new(big.Int).Mul(weight.BigInt(), big.NewInt(100))
+	rhs := new(big.Int).Mul(online.BigInt(), big.NewInt(int64(e.quorum)))
+	if lhs.Cmp(rhs) < 0 {
+		return nil, nil
+	}
+
+	// make sure the winner ends up in our ledger
+	blk := election.blocks[winner]
+	res, err := e.ledger.AddBlock(blk)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.Accepted():
+	case res == store.ProcessFork:
+		resolved, err := e.ledger.ResolveFork(election.Root, votes)
+		if err != nil {
+			return nil, err
+		}
+		if resolved != winner {
+			return nil, nil
+		}
+	default:
+		// the winner can't be added to the ledger yet, so keep the election
+		// going until it can
+		return nil, nil
+	}
+
+	delete(e.elections, election.Root)
+	if err := e.ledger.Confirm(winner); err != nil {
+		return nil, err
+	}
+
+	return blk, nil
+}
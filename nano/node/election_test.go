@@ -0,0 +1,104 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/crypto/ed25519"
+	"github.com/alexbakker/gonano/nano/store"
+	"github.com/alexbakker/gonano/nano/store/genesis"
+)
+
+type testAccount struct {
+	address nano.Address
+	key     ed25519.PrivateKey
+}
+
+func newTestAccount(t *testing.T) *testAccount {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var address nano.Address
+	copy(address[:], key.Public().(ed25519.PublicKey))
+	return &testAccount{address: address, key: key}
+}
+
+func (a *testAccount) sign(sig *block.Signature, hash block.Hash) {
+	copy(sig[:], ed25519.Sign(a.key, hash[:]))
+}
+
+// newTestLedger creates a ledger with an in-memory store. The genesis block of
+// the ledger has a work threshold of zero.
+func newTestLedger(t *testing.T) (*store.Ledger, store.Store, genesis.Genesis, *testAccount) {
+	genAcc := newTestAccount(t)
+	open := block.OpenBlock{SourceHash: block.Hash(genAcc.address), Representative: genAcc.address, Address: genAcc.address}
+	genAcc.sign(&open.Signature, open.Hash())
+	gen := genesis.Genesis{Block: open, Balance: nano.ParseBalanceInts(0, 1000000)}
+
+	db := store.NewMemoryStore()
+	ledger, err := store.NewLedger(db, store.LedgerOptions{Genesis: gen})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ledger, db, gen, genAcc
+}
+
+func TestElectionsVote(t *testing.T) {
+	ledger, _, gen, genAcc := newTestLedger(t)
+	newSend := func(previous block.Hash) *block.StateBlock {
+		blk := block.StateBlock{
+			Address:        genAcc.address,
+			PreviousHash:   previous,
+			Representative: genAcc.address,
+			Balance:        gen.Balance.Sub(nano.ParseBalanceInts(0, 1000)),
+		}
+		genAcc.sign(&blk.Signature, blk.Hash())
+		return &blk
+	}
+
+	var sequence uint64
+	vote := func(elections *Elections, blk block.Block) block.Block {
+		sequence++
+		vote := block.Vote{Address: genAcc.address, Sequence: sequence, Block: blk}
+		genAcc.sign(&vote.Signature, vote.Hash())
+
+		winner, err := elections.Vote(&vote)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return winner
+	}
+
+	elections := NewElections(ledger, 50, nano.ZeroBalance)
+
+	// a winner that can't be added to the ledger yet is not confirmed
+	var previous block.Hash
+	copy(previous[:], "unknown previous block")
+	gap := newSend(previous)
+	elections.Start(gap)
+	if winner := vote(elections, gap); winner != nil {
+		t.Fatalf("unexpected winner: %s", winner.Hash())
+	}
+	if confirmed, err := ledger.Confirmed(gap.Hash()); err != nil || confirmed {
+		t.Fatalf("block in a gap was confirmed (err: %v)", err)
+	}
+	if len(elections.Active()) != 1 {
+		t.Fatal("election of a block in a gap was removed")
+	}
+
+	send := newSend(gen.Block.Hash())
+	elections.Start(send)
+	if winner := vote(elections, send); winner == nil || winner.Hash() != send.Hash() {
+		t.Fatal("expected the send to win the election")
+	}
+	if confirmed, err := ledger.Confirmed(send.Hash()); err != nil || !confirmed {
+		t.Fatalf("winning block was not confirmed (err: %v)", err)
+	}
+	if len(elections.Active()) != 1 {
+		t.Fatal("expected only the election of the block in a gap to remain")
+	}
+}
@@ -0,0 +1,49 @@
+package node
+
+import (
+	"sync"
+
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+const (
+	blockFilterSize = 65536
+)
+
+// BlockFilter keeps track of the hashes of blocks we've seen recently. Once the
+// filter is full, the oldest hashes are forgotten first.
+type BlockFilter struct {
+	mutex  sync.Mutex
+	hashes map[block.Hash]struct{}
+	ring   []block.Hash
+	next   int
+}
+
+// NewBlockFilter creates a new block filter that remembers up to size hashes.
+func NewBlockFilter(size int) *BlockFilter {
+	return &BlockFilter{
+		hashes: make(map[block.Hash]struct{}, size),
+		ring:   make([]block.Hash, size),
+	}
+}
+
+// Add adds the given hash to the filter and reports whether it was already in
+// there.
+func (f *BlockFilter) Add(hash block.Hash) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, ok := f.hashes[hash]; ok {
+		return true
+	}
+
+	// make room by forgetting the oldest hash
+	if old := f.ring[f.next]; !old.IsZero() {
+		delete(f.hashes, old)
+	}
+
+	f.ring[f.next] = hash
+	f.next = (f.next + 1) % len(f.ring)
+	f.hashes[hash] = struct{}{}
+	return false
+}
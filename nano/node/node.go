@@ -1,8 +1,10 @@
 package node
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/alexbakker/gonano/nano/block"
 	"github.com/alexbakker/gonano/nano/node/proto"
 	"github.com/alexbakker/gonano/nano/store"
+	"github.com/alexbakker/gonano/nano/wallet"
 )
 
 var (
@@ -18,22 +21,27 @@ var (
 	errBadProtocol  = errors.New("unexpected protocol for this packet")
 
 	DefaultOptions = Options{
-		Network:      proto.NetworkLive,
-		Address:      ":7075",
-		EnableIPv6:   false,
-		EnableVoting: true,
-		MaxPeers:     15,
+		Network:             proto.NetworkLive,
+		Address:             ":7075",
+		EnableIPv6:          false,
+		EnableVoting:        true,
+		MaxPeers:            15,
+		Quorum:              50,
+		OnlineWeightMinimum: nano.ParseBalanceInts(0x2d239465031da916, 0x05b947c000000000),
 	}
 )
 
 type Node struct {
-	options Options
-	proto   *proto.Proto
-	udpConn *net.UDPConn
-	tcpConn *net.TCPListener
-	peers   *PeerList
-	ledger  *store.Ledger
-	stop    chan struct{}
+	options   Options
+	proto     *proto.Proto
+	udpConn   *net.UDPConn
+	tcpConn   *net.TCPListener
+	peers     *PeerList
+	ledger    *store.Ledger
+	elections *Elections
+	seen      *BlockFilter
+	stop      chan struct{}
+	pushers   chan struct{}
 
 	frontiers map[nano.Address]block.Hash
 }
@@ -45,6 +53,15 @@ type Options struct {
 	EnableVoting bool
 	MaxPeers     int
 	Peers        []string
+	// Quorum is the percentage of the online voting weight a block needs to
+	// receive votes from before it's considered confirmed.
+	Quorum int
+	// OnlineWeightMinimum is the lowest value the online voting weight is
+	// assumed to have when calculating the quorum.
+	OnlineWeightMinimum nano.Balance
+	// Representative is the account the node votes with if EnableVoting is
+	// set. If it's nil, the node doesn't vote.
+	Representative *wallet.Account
 }
 
 func New(ledger *store.Ledger, options Options) (*Node, error) {
@@ -75,7 +92,10 @@ func New(ledger *store.Ledger, options Options) (*Node, error) {
 		options:   options,
 		peers:     NewPeerList(options.MaxPeers),
 		ledger:    ledger,
+		elections: NewElections(ledger, options.Quorum, options.OnlineWeightMinimum),
+		seen:      NewBlockFilter(blockFilterSize),
 		stop:      make(chan struct{}),
+		pushers:   make(chan struct{}, pushMaxConns),
 		frontiers: map[nano.Address]block.Hash{},
 	}, nil
 }
@@ -92,8 +112,15 @@ func (n *Node) Run() error {
 		}
 	}
 
+	go func() {
+		if err := n.listenTCP(); err != nil {
+			fmt.Printf("error listening on tcp: %s\n", err)
+		}
+	}()
+
 	go n.syncFontiers()
 	go n.syncBlocks()
+	go n.announceElections()
 
 	return n.listenUDP()
 }
@@ -115,18 +142,100 @@ func (n *Node) Stop() error {
 }
 
 func (n *Node) Publish(blk block.Block) error {
+	// make sure we don't flood the block again when a peer sends it back
+	n.seen.Add(blk.Hash())
+
 	packet := proto.PublishPacket{
 		Type:  blk.ID(),
 		Block: blk,
 	}
 
+	return n.broadcast(&packet)
+}
+
+// Confirm starts an election for the given block and asks our peers to vote
+// on it. See Confirmed.
+func (n *Node) Confirm(blk block.Block) error {
+	n.elections.Start(blk)
+
+	if n.voting() {
+		if err := n.broadcastVote(blk); err != nil {
+			return err
+		}
+	}
+
+	return n.sendConfirmReq(blk)
+}
+
+// Confirmed reports whether the block with the given hash was confirmed by the
+// network.
+func (n *Node) Confirmed(hash block.Hash) (bool, error) {
+	return n.ledger.Confirmed(hash)
+}
+
+// Peers returns a copy of the list of peers we're currently connected to.
+func (n *Node) Peers() []*Peer {
+	return n.peers.Peers()
+}
+
+// Versions returns the protocol versions the node speaks.
+func (n *Node) Versions() proto.Versions {
+	return n.proto.Versions()
+}
+
+// voting reports whether this node votes as a representative.
+func (n *Node) voting() bool {
+	return n.options.EnableVoting && n.options.Representative != nil
+}
+
+// vote creates a signed vote for the given block with our representative key.
+// The vote is counted towards our own elections as well.
+func (n *Node) vote(blk block.Block) (*block.Vote, error) {
+	rep := n.options.Representative
+
+	// the sequence number is persisted before the vote leaves the node
+	sequence, err := n.ledger.NextVoteSequence(rep.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	vote := block.Vote{
+		Address:  rep.Address(),
+		Sequence: sequence,
+		Block:    blk,
+	}
+	vote.Signature = rep.Sign(vote.Hash())
+
+	if _, err := n.elections.Vote(&vote); err != nil {
+		return nil, err
+	}
+
+	return &vote, nil
+}
+
+// broadcastVote votes for the given block and sends the vote to a random
+// selection of our peers.
+func (n *Node) broadcastVote(blk block.Block) error {
+	vote, err := n.vote(blk)
+	if err != nil {
+		return err
+	}
+
+	return n.broadcast(&proto.ConfirmAckPacket{
+		Type: blk.ID(),
+		Vote: *vote,
+	})
+}
+
+// broadcast sends the given packet to a random selection of our peers.
+func (n *Node) broadcast(packet proto.Packet) error {
 	peers, err := n.peers.Pick()
 	if err != nil {
 		return err
 	}
 
 	for _, peer := range peers {
-		if err := n.sendPacket(peer.Addr, &packet); err != nil {
+		if err := n.sendPacket(peer.Addr, packet); err != nil {
 			return err
 		}
 	}
@@ -162,13 +271,84 @@ func (n *Node) listenUDP() error {
 			fmt.Printf("error handling packet: %s\n", err)
 			continue
 		}
-	}
 
-	return nil
+		// remember which protocol version the peer speaks
+		if peer := n.peers.Get(addr); peer != nil {
+			var header proto.Header
+			if err := header.UnmarshalBinary(data[:proto.HeaderSize]); err == nil {
+				peer.setVersion(header.VersionUsing)
+			}
+		}
+	}
 }
 
 func (n *Node) listenTCP() error {
-	return errors.New("not implemented")
+	for {
+		conn, err := n.tcpConn.AcceptTCP()
+		select {
+		case <-n.stop:
+			return nil
+		default:
+			// continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// limit the amount of peers we're serving at the same time
+		select {
+		case n.pushers <- struct{}{}:
+		default:
+			conn.Close()
+			continue
+		}
+
+		go func() {
+			defer func() { <-n.pushers }()
+
+			if err := n.serveTCP(conn); err != nil {
+				fmt.Printf("error serving %s: %s\n", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// serveTCP answers bootstrap requests received on the given connection until
+// the peer closes it or stays idle for too long.
+func (n *Node) serveTCP(conn *net.TCPConn) error {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(&timeoutWriter{conn: conn, timeout: syncTimeout})
+
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(pushIdleTimeout)); err != nil {
+			return err
+		}
+
+		packet, err := n.proto.ReadPacket(reader)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		fmt.Printf("serving %s to %s\n", proto.Name(packet.ID()), conn.RemoteAddr())
+
+		pusher, err := NewPusher(n.ledger, packet)
+		if err != nil {
+			return err
+		}
+
+		if err := pusher.Push(writer); err != nil {
+			return err
+		}
+
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
 }
 
 // syncFrontiers asks a random peer for a list of frontiers once every 5
@@ -222,6 +402,27 @@ func (n *Node) syncBlocks() error {
 	return nil
 }
 
+// announceElections periodically asks our peers to vote on the blocks of all
+// active elections.
+func (n *Node) announceElections() {
+	ticker := time.NewTicker(electionAnnounceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+		}
+
+		for _, blk := range n.elections.Active() {
+			if err := n.sendConfirmReq(blk); err != nil {
+				fmt.Printf("error requesting votes: %s\n", err)
+			}
+		}
+	}
+}
+
 func (n *Node) processFrontier(frontier *block.Frontier) {
 	/*head, err := n.ledger.GetFrontier(frontier.Address)
 	if err != nil && err != store.ErrNotFound {
@@ -240,8 +441,18 @@ func (n *Node) processFrontier(frontier *block.Frontier) {
 }
 
 func (n *Node) processFrontierBlocks(blocks []block.Block) {
-	if err := n.ledger.AddBlocks(blocks); err != nil {
-		//fmt.Printf("error adding block: %s\n", err)
+	results, err := n.ledger.AddBlocks(blocks)
+	if err != nil {
+		fmt.Printf("error adding blocks: %s\n", err)
+		return
+	}
+
+	for i, res := range results {
+		switch res {
+		case store.ProcessProgress, store.ProcessOld, store.ProcessGapPrevious, store.ProcessGapSource:
+		default:
+			fmt.Printf("rejected block %s: %s\n", blocks[i].Hash(), res)
+		}
 	}
 }
 
@@ -284,6 +495,15 @@ func (n *Node) sendPacket(addr *net.UDPAddr, packet proto.Packet) error {
 	return err
 }
 
+func (n *Node) sendConfirmReq(blk block.Block) error {
+	packet := proto.ConfirmReqPacket{
+		Type:  blk.ID(),
+		Block: blk,
+	}
+
+	return n.broadcast(&packet)
+}
+
 func (n *Node) sendKeepAlive(target *Peer) error {
 	// pick a couple of random peers to share
 	peers, err := n.peers.Pick()
@@ -309,12 +529,14 @@ func (n *Node) handlePacket(addr *net.UDPAddr, packet proto.Packet) error {
 	case *proto.KeepAlivePacket:
 		return n.handleKeepAlivePacket(addr, p)
 	case *proto.ConfirmAckPacket:
+		return n.handleConfirmAckPacket(addr, p)
 	case *proto.ConfirmReqPacket:
+		return n.handleConfirmReqPacket(addr, p)
 	case *proto.PublishPacket:
+		return n.handlePublishPacket(addr, p)
 	default:
 		return errBadProtocol
 	}
-	return nil
 }
 
 func (n *Node) handleKeepAlivePacket(addr *net.UDPAddr, packet *proto.KeepAlivePacket) error {
@@ -351,3 +573,108 @@ func (n *Node) handleKeepAlivePacket(addr *net.UDPAddr, packet *proto.KeepAliveP
 
 	return nil
 }
+
+func (n *Node) handleConfirmAckPacket(addr *net.UDPAddr, packet *proto.ConfirmAckPacket) error {
+	_, err := n.elections.Vote(&packet.Vote)
+	return err
+}
+
+func (n *Node) handleConfirmReqPacket(addr *net.UDPAddr, packet *proto.ConfirmReqPacket) error {
+	// the block we vote for: either the requested one or the one it competes
+	// with in our ledger
+	blk := packet.Block
+
+	res, err := n.ledger.AddBlock(blk)
+	if err != nil {
+		return err
+	}
+
+	// if the block competes with one in our ledger, start an election for it
+	if res == store.ProcessFork {
+		forks, err := n.startForkElection(blk)
+		if err != nil {
+			return err
+		}
+
+		// the block that's currently in our ledger comes first
+		if _, err := n.ledger.GetBlock(forks[0].Hash()); err == nil {
+			blk = forks[0]
+			res = store.ProcessOld
+		}
+	}
+
+	// only vote for blocks that made it into our ledger
+	if !n.voting() || !res.Accepted() {
+		return nil
+	}
+
+	vote, err := n.vote(blk)
+	if err != nil {
+		return err
+	}
+
+	return n.sendPacket(addr, &proto.ConfirmAckPacket{
+		Type: blk.ID(),
+		Vote: *vote,
+	})
+}
+
+func (n *Node) handlePublishPacket(addr *net.UDPAddr, packet *proto.PublishPacket) error {
+	blk := packet.Block
+	hash := blk.Hash()
+
+	// ignore blocks we've processed recently
+	if n.seen.Add(hash) {
+		return nil
+	}
+
+	res, err := n.ledger.AddBlock(blk)
+	if err != nil {
+		return err
+	}
+
+	switch res {
+	case store.ProcessProgress:
+		// flood blocks that are new to our ledger
+	case store.ProcessFork:
+		// let the network decide which of the competing blocks wins
+		forks, err := n.startForkElection(blk)
+		if err != nil {
+			return err
+		}
+
+		for _, fork := range forks {
+			if err := n.sendConfirmReq(fork); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	if err := n.broadcast(packet); err != nil {
+		return err
+	}
+
+	if n.voting() {
+		return n.broadcastVote(blk)
+	}
+
+	return nil
+}
+
+// startForkElection starts an election for all blocks that compete with the
+// given block and returns them.
+func (n *Node) startForkElection(blk block.Block) ([]block.Block, error) {
+	forks, err := n.ledger.Forks(store.ForkRoot(blk))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fork := range forks {
+		n.elections.Start(fork)
+	}
+
+	return forks, nil
+}
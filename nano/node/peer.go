@@ -2,6 +2,7 @@ package node
 
 import (
 	"net"
+	"sync"
 	"time"
 )
 
@@ -15,6 +16,9 @@ type Peer struct {
 	Addr     *net.UDPAddr
 	lastPing time.Time
 	lastPong time.Time
+
+	mutex   sync.Mutex
+	version byte
 }
 
 // Ping will call the given function if the peer needs to be pinged. If fn
@@ -48,3 +52,19 @@ func (p *Peer) Dead() bool {
 func (p *Peer) Pong() {
 	p.lastPong = time.Now()
 }
+
+// Version returns the protocol version this peer used in the last packet we
+// received from it. It's zero if we haven't received anything from it yet.
+func (p *Peer) Version() byte {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.version
+}
+
+func (p *Peer) setVersion(version byte) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.version = version
+}
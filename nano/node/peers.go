@@ -3,6 +3,7 @@ package node
 import (
 	"errors"
 	"net"
+	"sync"
 
 	"github.com/alexbakker/gonano/nano/crypto/random"
 )
@@ -12,8 +13,9 @@ var (
 	ErrPeerExists = errors.New("this peer already exists in the list")
 )
 
-// PeerList represents a list of peers.
+// PeerList represents a list of peers. It is safe for concurrent use.
 type PeerList struct {
+	mutex sync.RWMutex
 	peers []*Peer
 	max   int
 }
@@ -26,13 +28,16 @@ func NewPeerList(max int) *PeerList {
 // Add creates a new peer instance with the given address, adds it to the
 // internal peer list and returns it.
 func (l *PeerList) Add(addr *net.UDPAddr) (*Peer, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
 	// enforce a maximum amount of peers
-	if l.Full() {
+	if l.full() {
 		return nil, ErrMaxPeers
 	}
 
 	// check if we already have this peer in our list
-	if l.Get(addr) != nil {
+	if l.get(addr) != nil {
 		return nil, ErrPeerExists
 	}
 
@@ -44,6 +49,13 @@ func (l *PeerList) Add(addr *net.UDPAddr) (*Peer, error) {
 // Get retrieves a peer with the given address. If no such peer exists, nil is
 // returned.
 func (l *PeerList) Get(addr *net.UDPAddr) *Peer {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.get(addr)
+}
+
+func (l *PeerList) get(addr *net.UDPAddr) *Peer {
 	for _, peer := range l.peers {
 		if peer.Addr.IP.Equal(addr.IP) && peer.Addr.Port == addr.Port {
 			return peer
@@ -55,6 +67,9 @@ func (l *PeerList) Get(addr *net.UDPAddr) *Peer {
 
 // Remove removes the given peer from the list.
 func (l *PeerList) Remove(peer *Peer) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
 	for i := range l.peers {
 		if l.peers[i] == peer {
 			l.peers = append(l.peers[:i], l.peers[i+1:]...)
@@ -66,17 +81,30 @@ func (l *PeerList) Remove(peer *Peer) {
 
 // Full reports whether the internal peer list has reached its maximum capacity.
 func (l *PeerList) Full() bool {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.full()
+}
+
+func (l *PeerList) full() bool {
 	return len(l.peers) >= l.max
 }
 
 // Len returns the length of the internal peer list.
 func (l *PeerList) Len() int {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
 	return len(l.peers)
 }
 
 // Pick returns 8 random peers from the internal peer list. This function is
 // usually used to populate a KeepAlivePacket.
 func (l *PeerList) Pick() ([]*Peer, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
 	var size int
 	if len(l.peers) > 8 {
 		size = 8
@@ -99,6 +127,9 @@ func (l *PeerList) Pick() ([]*Peer, error) {
 
 // Random picks one random peer from the internal peer list and returns it.
 func (l *PeerList) Random() (*Peer, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
 	i, err := random.Intn(len(l.peers))
 	if err != nil {
 		return nil, err
@@ -109,6 +140,9 @@ func (l *PeerList) Random() (*Peer, error) {
 
 // Peers returns a copy of the internal peer list.
 func (l *PeerList) Peers() []*Peer {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
 	peers := make([]*Peer, len(l.peers))
 	copy(peers, l.peers)
 	return peers
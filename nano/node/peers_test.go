@@ -0,0 +1,49 @@
+package node
+
+import (
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestPeerListConcurrent(t *testing.T) {
+	list := NewPeerList(64)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 8; j++ {
+				addr := &net.UDPAddr{IP: net.IPv4(10, 0, byte(i), byte(j)), Port: 7075}
+				peer, err := list.Add(addr)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if list.Get(addr) != peer {
+					t.Error("peer not found after adding it")
+					return
+				}
+
+				list.Peers()
+				if _, err := list.Pick(); err != nil {
+					t.Error(err)
+					return
+				}
+				if j%2 == 0 {
+					list.Remove(peer)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := list.Len(); n != 32 {
+		t.Fatalf("unexpected amount of peers: %d", n)
+	}
+	if _, err := list.Add(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 7075}); err != ErrPeerExists {
+		t.Fatalf("unexpected error adding a duplicate peer: %v", err)
+	}
+}
@@ -9,6 +9,11 @@ import (
 	"github.com/alexbakker/gonano/nano/internal/util"
 )
 
+const (
+	BulkPullSize       = nano.AddressSize + block.HashSize
+	BulkPullBlocksSize = block.HashSize*2 + 1 + 4
+)
+
 type BulkPullMode byte
 
 const (
@@ -8,6 +8,10 @@ import (
 	"github.com/alexbakker/gonano/nano/internal/util"
 )
 
+const (
+	FrontierReqSize = nano.AddressSize + 4 + 4
+)
+
 type FrontierReqPacket struct {
 	StartAddress nano.Address
 	Age          uint32
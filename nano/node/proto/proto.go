@@ -2,6 +2,7 @@ package proto
 
 import (
 	"fmt"
+	"io"
 	"net"
 )
 
@@ -45,6 +46,11 @@ func New(net Network) *Proto {
 	}
 }
 
+// Versions returns the protocol versions this instance speaks.
+func (p *Proto) Versions() Versions {
+	return p.versions
+}
+
 func (p *Proto) NewHeader(packetType byte) *Header {
 	return &Header{
 		Magic:        p.magic,
@@ -110,6 +116,39 @@ func (p *Proto) UnmarshalPacket(data []byte) (Packet, error) {
 	return packet, nil
 }
 
+// ReadPacket reads the next packet from the given stream. Only packets with a
+// fixed size (the ones that are sent over TCP) are supported.
+func (p *Proto) ReadPacket(r io.Reader) (Packet, error) {
+	data := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	var header Header
+	if err := header.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	var size int
+	switch header.MessageType {
+	case idPacketBulkPull:
+		size = BulkPullSize
+	case idPacketFrontierReq:
+		size = FrontierReqSize
+	case idPacketBulkPullBlocks:
+		size = BulkPullBlocksSize
+	default:
+		return nil, ErrBadType
+	}
+
+	data = append(data, make([]byte, size)...)
+	if _, err := io.ReadFull(r, data[HeaderSize:]); err != nil {
+		return nil, err
+	}
+
+	return p.UnmarshalPacket(data)
+}
+
 func (p *Proto) MarshalPacket(packet Packet) ([]byte, error) {
 	header := p.NewHeader(packet.ID())
 
@@ -118,6 +157,8 @@ func (p *Proto) MarshalPacket(packet Packet) ([]byte, error) {
 		header.SetBlockType(t.Type)
 	case *PublishPacket:
 		header.SetBlockType(t.Type)
+	case *ConfirmAckPacket:
+		header.SetBlockType(t.Type)
 	}
 
 	headerBytes, err := header.MarshalBinary()
@@ -1,13 +1,211 @@
 package node
 
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/node/proto"
+	"github.com/alexbakker/gonano/nano/store"
+)
+
+const (
+	pushIdleTimeout = time.Second * 30
+	pushMaxConns    = 16
+)
+
+var errPushDone = errors.New("push done")
+
+// Pusher is the counterpart of Syncer. It answers a single bootstrap request
+// that was received from a peer.
 type Pusher interface {
+	// Push writes the response to the request this pusher was created for to
+	// the given writer.
+	Push(w io.Writer) error
 }
 
 type FrontierPusher struct {
+	ledger *store.Ledger
+	packet *proto.FrontierReqPacket
 }
 
 type BulkPullPusher struct {
+	ledger *store.Ledger
+	packet *proto.BulkPullPacket
 }
 
 type BulkPullBlocksPusher struct {
+	ledger *store.Ledger
+	packet *proto.BulkPullBlocksPacket
+}
+
+// timeoutWriter resets the write deadline of the underlying connection before
+// every write.
+type timeoutWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func NewFrontierPusher(ledger *store.Ledger, packet *proto.FrontierReqPacket) *FrontierPusher {
+	return &FrontierPusher{ledger: ledger, packet: packet}
+}
+
+func NewBulkPullPusher(ledger *store.Ledger, packet *proto.BulkPullPacket) *BulkPullPusher {
+	return &BulkPullPusher{ledger: ledger, packet: packet}
+}
+
+func NewBulkPullBlocksPusher(ledger *store.Ledger, packet *proto.BulkPullBlocksPacket) *BulkPullBlocksPusher {
+	return &BulkPullBlocksPusher{ledger: ledger, packet: packet}
+}
+
+// NewPusher creates a pusher that answers the given request packet.
+func NewPusher(ledger *store.Ledger, packet proto.Packet) (Pusher, error) {
+	switch p := packet.(type) {
+	case *proto.FrontierReqPacket:
+		return NewFrontierPusher(ledger, p), nil
+	case *proto.BulkPullPacket:
+		return NewBulkPullPusher(ledger, p), nil
+	case *proto.BulkPullBlocksPacket:
+		return NewBulkPullBlocksPusher(ledger, p), nil
+	default:
+		return nil, errBadProtocol
+	}
+}
+
+// Push implements the Pusher interface. Only the frontiers of accounts that
+// were modified in the last Age seconds are sent, unless Age is the maximum
+// value.
+func (p *FrontierPusher) Push(w io.Writer) error {
+	var since time.Time
+	if p.packet.Age != math.MaxUint32 {
+		since = time.Now().Add(-time.Duration(p.packet.Age) * time.Second)
+	}
+
+	var count uint32
+	err := p.ledger.WalkFrontiers(p.packet.StartAddress, since, func(frontier *block.Frontier) error {
+		if count >= p.packet.Count {
+			return errPushDone
+		}
+		count++
+
+		return writeFrontier(w, frontier)
+	})
+	if err != nil && err != errPushDone {
+		return err
+	}
+
+	// a zeroed frontier marks the end of the transmission
+	return writeFrontier(w, &block.Frontier{})
+}
+
+// Push implements the Pusher interface.
+func (p *BulkPullPusher) Push(w io.Writer) error {
+	info, err := p.ledger.GetAddressInfo(p.packet.Address)
+	if err != nil && err != store.ErrNotFound {
+		return err
+	}
+
+	// walk the chain of the account back from its head until we arrive at
+	// the requested end block
+	if err == nil {
+		for hash := info.HeadBlock; !hash.IsZero() && hash != p.packet.Hash; {
+			blk, err := p.ledger.GetBlock(hash)
+			if err != nil {
+				return err
+			}
+
+			if err := writeBlock(w, blk); err != nil {
+				return err
+			}
+
+			hash = blk.Previous()
+		}
+	}
+
+	return writeNotABlock(w)
+}
+
+// Push implements the Pusher interface. In checksum mode, the XOR of the
+// hashes of all blocks in the requested range is sent after the end marker
+// instead of the blocks themselves.
+func (p *BulkPullBlocksPusher) Push(w io.Writer) error {
+	var count uint32
+	var checksum block.Hash
+
+	err := p.ledger.WalkBlocks(p.packet.Min, func(blk block.Block) error {
+		hash := blk.Hash()
+		// blocks are visited in order of their hash, so we're done here
+		if bytes.Compare(hash[:], p.packet.Max[:]) > 0 || count >= p.packet.Count {
+			return errPushDone
+		}
+		count++
+
+		switch p.packet.Mode {
+		case proto.BulkPullModeList:
+			return writeBlock(w, blk)
+		case proto.BulkPullModeChecksum:
+			for i := range checksum {
+				checksum[i] ^= hash[i]
+			}
+			return nil
+		default:
+			return errBadProtocol
+		}
+	})
+	if err != nil && err != errPushDone {
+		return err
+	}
+
+	if err := writeNotABlock(w); err != nil {
+		return err
+	}
+
+	if p.packet.Mode == proto.BulkPullModeChecksum {
+		_, err = w.Write(checksum[:])
+		return err
+	}
+
+	return nil
+}
+
+// Write implements the io.Writer interface.
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.timeout)); err != nil {
+		return 0, err
+	}
+
+	return w.conn.Write(p)
+}
+
+func writeFrontier(w io.Writer, frontier *block.Frontier) error {
+	frontierBytes, err := frontier.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(frontierBytes)
+	return err
+}
+
+func writeBlock(w io.Writer, blk block.Block) error {
+	blockBytes, err := blk.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write([]byte{blk.ID()}); err != nil {
+		return err
+	}
+
+	_, err = w.Write(blockBytes)
+	return err
+}
+
+func writeNotABlock(w io.Writer) error {
+	_, err := w.Write([]byte{block.IDNotABlock})
+	return err
 }
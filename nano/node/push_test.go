@@ -0,0 +1,194 @@
+package node
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/node/proto"
+	"github.com/alexbakker/gonano/nano/store"
+)
+
+func TestFrontierPusher(t *testing.T) {
+	ledger, db, gen, genAcc := newTestLedger(t)
+	acc := newTestAccount(t)
+
+	amount := nano.ParseBalanceInts(0, 1000)
+	send := block.StateBlock{
+		Address:        genAcc.address,
+		PreviousHash:   gen.Block.Hash(),
+		Representative: genAcc.address,
+		Balance:        gen.Balance.Sub(amount),
+		Link:           block.Hash(acc.address),
+	}
+	genAcc.sign(&send.Signature, send.Hash())
+	open := block.StateBlock{
+		Address:        acc.address,
+		Representative: acc.address,
+		Balance:        amount,
+		Link:           send.Hash(),
+	}
+	acc.sign(&open.Signature, open.Hash())
+	for _, blk := range []block.Block{&send, &open} {
+		if res, err := ledger.AddBlock(blk); err != nil || res != store.ProcessProgress {
+			t.Fatalf("unexpected result adding block %s: %s (err: %v)", blk.Hash(), res, err)
+		}
+	}
+
+	// pretend the genesis account was last modified an hour ago
+	err := db.Update(func(txn store.StoreTxn) error {
+		sideband, err := txn.GetSideband(send.Hash())
+		if err != nil {
+			return err
+		}
+
+		sideband.Timestamp = time.Now().Add(-time.Hour).Unix()
+		return txn.UpdateSideband(send.Hash(), sideband)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frontiers := map[nano.Address]block.Hash{genAcc.address: send.Hash(), acc.address: open.Hash()}
+	tests := []struct {
+		age   uint32
+		count uint32
+		n     int
+	}{
+		{age: math.MaxUint32, count: math.MaxUint32, n: 2},
+		{age: math.MaxUint32, count: 1, n: 1},
+		{age: 60, count: math.MaxUint32, n: 1},
+		{age: 7200, count: math.MaxUint32, n: 2},
+	}
+
+	for i, test := range tests {
+		packet := proto.FrontierReqPacket{Age: test.age, Count: test.count}
+		var buf bytes.Buffer
+		if err := NewFrontierPusher(ledger, &packet).Push(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		var res []*block.Frontier
+		syncer := NewFrontierSyncer(func(frontier *block.Frontier) {
+			res = append(res, frontier)
+		})
+		readAll(t, syncer, &buf)
+
+		if len(res) != test.n {
+			t.Fatalf("unexpected amount of frontiers for test %d: %d", i, len(res))
+		}
+		for _, frontier := range res {
+			if frontiers[frontier.Address] != frontier.Hash {
+				t.Fatalf("unexpected frontier for test %d: %s", i, frontier.Address)
+			}
+		}
+		if test.age == 60 && res[0].Address != acc.address {
+			t.Fatalf("frontier of an account that wasn't modified recently was sent")
+		}
+	}
+}
+
+func TestBulkPullBlocksPusher(t *testing.T) {
+	ledger, _, gen, genAcc := newTestLedger(t)
+
+	hashes := []block.Hash{gen.Block.Hash()}
+	balance := gen.Balance
+	for i := 0; i < 8; i++ {
+		balance = balance.Sub(nano.ParseBalanceInts(0, 1000))
+		send := block.StateBlock{
+			Address:        genAcc.address,
+			PreviousHash:   hashes[len(hashes)-1],
+			Representative: genAcc.address,
+			Balance:        balance,
+		}
+		genAcc.sign(&send.Signature, send.Hash())
+		if res, err := ledger.AddBlock(&send); err != nil || res != store.ProcessProgress {
+			t.Fatalf("unexpected result adding block %s: %s (err: %v)", send.Hash(), res, err)
+		}
+
+		hashes = append(hashes, send.Hash())
+	}
+
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+
+	tests := []struct {
+		min, max int
+		count    uint32
+		res      []block.Hash
+	}{
+		{min: 0, max: 8, count: math.MaxUint32, res: hashes},
+		{min: 2, max: 5, count: math.MaxUint32, res: hashes[2:6]},
+		{min: 2, max: 5, count: 2, res: hashes[2:4]},
+		{min: 8, max: 0, count: math.MaxUint32, res: nil},
+	}
+
+	for i, test := range tests {
+		packet := proto.BulkPullBlocksPacket{
+			Min:   hashes[test.min],
+			Max:   hashes[test.max],
+			Mode:  proto.BulkPullModeList,
+			Count: test.count,
+		}
+
+		var buf bytes.Buffer
+		if err := NewBulkPullBlocksPusher(ledger, &packet).Push(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		var res []block.Hash
+		syncer := NewBulkPullBlocksSyncer(func(blocks []block.Block) {
+			for _, blk := range blocks {
+				res = append(res, blk.Hash())
+			}
+		})
+		readAll(t, syncer, &buf)
+
+		if len(res) != len(test.res) {
+			t.Fatalf("unexpected amount of blocks for test %d: %d", i, len(res))
+		}
+		for j := range res {
+			if res[j] != test.res[j] {
+				t.Fatalf("unexpected block %d for test %d: %s", j, i, res[j])
+			}
+		}
+
+		// the checksum of the same range is the XOR of the hashes
+		packet.Mode = proto.BulkPullModeChecksum
+		buf.Reset()
+		if err := NewBulkPullBlocksPusher(ledger, &packet).Push(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		var expected block.Hash
+		for _, hash := range test.res {
+			for j := range expected {
+				expected[j] ^= hash[j]
+			}
+		}
+		if data := buf.Bytes(); len(data) != 1+block.HashSize || data[0] != block.IDNotABlock || !bytes.Equal(data[1:], expected[:]) {
+			t.Fatalf("unexpected checksum for test %d: %x", i, data)
+		}
+	}
+}
+
+// readAll feeds everything in the given reader to the syncer.
+func readAll(t *testing.T, syncer Syncer, r io.Reader) {
+	for {
+		done, err := syncer.ReadNext(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done {
+			break
+		}
+	}
+
+	syncer.Flush()
+}
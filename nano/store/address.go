@@ -14,6 +14,10 @@ type AddressInfo struct {
 	RepBlock  block.Hash
 	OpenBlock block.Hash
 	Balance   nano.Balance
+	// BlockCount is the amount of blocks on the account chain.
+	BlockCount uint64
+	// Epoch is the epoch the account was upgraded to.
+	Epoch block.Epoch
 }
 
 // MarshalBinary implements the encoding.BinaryMarshaler interface.
@@ -37,6 +41,14 @@ func (i *AddressInfo) MarshalBinary() ([]byte, error) {
 		return nil, err
 	}
 
+	if err = binary.Write(buf, binary.BigEndian, i.BlockCount); err != nil {
+		return nil, err
+	}
+
+	if err = buf.WriteByte(byte(i.Epoch)); err != nil {
+		return nil, err
+	}
+
 	return buf.Bytes(), nil
 }
 
@@ -65,5 +77,25 @@ func (i *AddressInfo) UnmarshalBinary(data []byte) error {
 		return err
 	}
 
+	// the block count is missing in databases with schema version 0, it's
+	// filled in by the migration to version 1
+	i.BlockCount = 0
+	if reader.Len() > 0 {
+		if err = binary.Read(reader, binary.BigEndian, &i.BlockCount); err != nil {
+			return err
+		}
+	}
+
+	// the epoch is missing in databases with schema version 1 and older, which
+	// only have accounts in the first epoch
+	i.Epoch = block.Epoch0
+	if reader.Len() > 0 {
+		epoch, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		i.Epoch = block.Epoch(epoch)
+	}
+
 	return util.AssertReaderEOF(reader)
 }
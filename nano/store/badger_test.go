@@ -20,9 +20,8 @@ func generateBlock(t testing.TB) block.Block {
 
 func TestBadgerWrite(t *testing.T) {
 	const n = 100000
-	ledger := initTestLedger(t)
-	store := ledger.store
-	//defer ledger.Close(t)
+	store, cleanup := openTestBadgerStore(t)
+	defer cleanup()
 
 	start := time.Now()
 	err := store.Update(func(txn StoreTxn) error {
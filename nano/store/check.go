@@ -0,0 +1,544 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+// The kinds of issues reported by Check.
+const (
+	CheckIssueChain    = "chain"
+	CheckIssueAccount  = "account"
+	CheckIssueFrontier = "frontier"
+	CheckIssuePending  = "pending"
+	CheckIssueWeight   = "weight"
+)
+
+// CheckIssue describes a difference between the state of the ledger in the
+// store and the state that follows from the blocks in it.
+type CheckIssue struct {
+	Kind    string
+	Message string
+}
+
+type pendingKey struct {
+	destination nano.Address
+	hash        block.Hash
+}
+
+// ledgerState is the state of the ledger that follows from the blocks in it.
+type ledgerState struct {
+	accounts  map[nano.Address]*AddressInfo
+	frontiers map[block.Hash]nano.Address
+	pending   map[pendingKey]*Pending
+	weights   map[nano.Address]nano.Balance
+}
+
+// String implements the fmt.Stringer interface.
+func (i *CheckIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Kind, i.Message)
+}
+
+// Check recomputes the balance, head block, frontier and pending transactions
+// of every account and the weight of every representative from the account
+// chains, and compares them to what's in the store. If repair is set, the
+// store is updated to match the recomputed state. Issues with the chains
+// themselves are reported, but can't be repaired.
+func (l *Ledger) Check(repair bool) ([]*CheckIssue, error) {
+	var issues []*CheckIssue
+	check := func(txn StoreTxn) (err error) {
+		issues, err = l.check(txn, repair)
+		return err
+	}
+
+	var err error
+	if repair {
+		err = l.db.Update(check)
+	} else {
+		err = l.db.View(check)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return issues, nil
+}
+
+func (l *Ledger) check(txn StoreTxn, repair bool) ([]*CheckIssue, error) {
+	var issues []*CheckIssue
+	report := func(kind string, format string, a ...interface{}) {
+		issues = append(issues, &CheckIssue{Kind: kind, Message: fmt.Sprintf(format, a...)})
+	}
+
+	state, err := l.computeState(txn, report)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.checkAccounts(txn, state, repair, report); err != nil {
+		return nil, err
+	}
+	if err := l.checkFrontiers(txn, state, repair, report); err != nil {
+		return nil, err
+	}
+	if err := l.checkPending(txn, state, repair, report); err != nil {
+		return nil, err
+	}
+	if err := l.checkWeights(txn, state, repair, report); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Kind != issues[j].Kind {
+			return issues[i].Kind < issues[j].Kind
+		}
+		return issues[i].Message < issues[j].Message
+	})
+
+	return issues, nil
+}
+
+// computeState walks all account chains from their open block to their head
+// block to compute the state of the ledger.
+func (l *Ledger) computeState(txn StoreTxn, report func(kind string, format string, a ...interface{})) (*ledgerState, error) {
+	var opens []block.Hash
+	var total int
+	successors := map[block.Hash]block.Hash{}
+
+	err := txn.WalkBlocks(func(blk block.Block) error {
+		hash := blk.Hash()
+		total++
+
+		prevHash := blk.Previous()
+		if prevHash.IsZero() {
+			opens = append(opens, hash)
+			return nil
+		}
+
+		if other, ok := successors[prevHash]; ok {
+			report(CheckIssueChain, "blocks %s and %s both follow block %s", other, hash, prevHash)
+		}
+		successors[prevHash] = hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state := ledgerState{
+		accounts:  map[nano.Address]*AddressInfo{},
+		frontiers: map[block.Hash]nano.Address{},
+		pending:   map[pendingKey]*Pending{},
+		weights:   map[nano.Address]nano.Balance{},
+	}
+
+	var visited int
+	sent := map[block.Hash]pendingKey{}
+	received := map[block.Hash]struct{}{}
+	balances := map[block.Hash]nano.Balance{}
+	epochs := map[block.Hash]block.Epoch{}
+
+	for _, openHash := range opens {
+		var address nano.Address
+		var rep nano.Address
+		info := AddressInfo{OpenBlock: openHash}
+
+		for hash := openHash; !hash.IsZero(); hash = successors[hash] {
+			blk, err := txn.GetBlock(hash)
+			if err != nil {
+				return nil, err
+			}
+
+			balance, err := l.computeBalance(txn, hash, balances)
+			if err != nil {
+				return nil, err
+			}
+
+			epoch, err := l.computeEpoch(txn, hash, balances, epochs)
+			if err != nil {
+				return nil, err
+			}
+
+			switch b := blk.(type) {
+			case *block.OpenBlock:
+				address = b.Address
+				rep = b.Representative
+				info.RepBlock = hash
+				received[b.SourceHash] = struct{}{}
+			case *block.SendBlock:
+				key := pendingKey{destination: b.Destination, hash: hash}
+				state.pending[key] = &Pending{Address: address, Amount: info.Balance.Sub(balance), Epoch: epoch}
+				sent[hash] = key
+			case *block.ReceiveBlock:
+				received[b.SourceHash] = struct{}{}
+			case *block.ChangeBlock:
+				rep = b.Representative
+				info.RepBlock = hash
+			case *block.StateBlock:
+				address = b.Address
+				rep = b.Representative
+				info.RepBlock = hash
+
+				switch balance.Compare(info.Balance) {
+				case nano.BalanceCompBigger:
+					received[b.Link] = struct{}{}
+				case nano.BalanceCompSmaller:
+					key := pendingKey{destination: nano.Address(b.Link), hash: hash}
+					state.pending[key] = &Pending{Address: address, Amount: info.Balance.Sub(balance), Epoch: epoch}
+					sent[hash] = key
+				}
+			}
+
+			info.HeadBlock = hash
+			info.Balance = balance
+			info.BlockCount++
+			info.Epoch = epoch
+			visited++
+		}
+
+		if _, ok := state.accounts[address]; ok {
+			report(CheckIssueChain, "account %s has more than one open block", address)
+			continue
+		}
+
+		state.accounts[address] = &info
+		state.frontiers[info.HeadBlock] = address
+		state.weights[rep] = state.weights[rep].Add(info.Balance)
+	}
+
+	if visited != total {
+		report(CheckIssueChain, "%d blocks are not part of an account chain", total-visited)
+	}
+
+	// whatever was sent but not received yet is pending
+	for hash, key := range sent {
+		if _, ok := received[hash]; ok {
+			delete(state.pending, key)
+		}
+	}
+
+	return &state, nil
+}
+
+func (l *Ledger) checkAccounts(txn StoreTxn, state *ledgerState, repair bool, report func(kind string, format string, a ...interface{})) error {
+	stored := map[nano.Address]*AddressInfo{}
+	err := txn.WalkAddresses(func(address nano.Address, info *AddressInfo) error {
+		stored[address] = info
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for address := range stored {
+		if _, ok := state.accounts[address]; ok {
+			continue
+		}
+
+		report(CheckIssueAccount, "account %s has no blocks", address)
+		if repair {
+			if err := txn.DeleteAddress(address); err != nil {
+				return err
+			}
+		}
+	}
+
+	for address, expected := range state.accounts {
+		info, ok := stored[address]
+		if !ok {
+			report(CheckIssueAccount, "account %s is missing", address)
+		} else {
+			if info.HeadBlock != expected.HeadBlock {
+				report(CheckIssueAccount, "head block of %s is %s, expected %s", address, info.HeadBlock, expected.HeadBlock)
+			}
+			if info.OpenBlock != expected.OpenBlock {
+				report(CheckIssueAccount, "open block of %s is %s, expected %s", address, info.OpenBlock, expected.OpenBlock)
+			}
+			if info.RepBlock != expected.RepBlock {
+				report(CheckIssueAccount, "representative block of %s is %s, expected %s", address, info.RepBlock, expected.RepBlock)
+			}
+			if !info.Balance.Equal(expected.Balance) {
+				report(CheckIssueAccount, "balance of %s is %s, expected %s", address, info.Balance, expected.Balance)
+			}
+			if info.BlockCount != expected.BlockCount {
+				report(CheckIssueAccount, "block count of %s is %d, expected %d", address, info.BlockCount, expected.BlockCount)
+			}
+			if info.Epoch != expected.Epoch {
+				report(CheckIssueAccount, "epoch of %s is %s, expected %s", address, info.Epoch, expected.Epoch)
+			}
+			if *info == *expected {
+				continue
+			}
+		}
+
+		if repair {
+			if err := txn.UpdateAddress(address, expected); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *Ledger) checkFrontiers(txn StoreTxn, state *ledgerState, repair bool, report func(kind string, format string, a ...interface{})) error {
+	frontiers, err := txn.GetFrontiers()
+	if err != nil {
+		return err
+	}
+
+	stored := map[block.Hash]nano.Address{}
+	for _, frontier := range frontiers {
+		stored[frontier.Hash] = frontier.Address
+
+		address, ok := state.frontiers[frontier.Hash]
+		if ok && address == frontier.Address {
+			continue
+		}
+
+		if ok {
+			report(CheckIssueFrontier, "frontier %s belongs to %s, not %s", frontier.Hash, address, frontier.Address)
+		} else {
+			report(CheckIssueFrontier, "block %s of %s is not a head block", frontier.Hash, frontier.Address)
+		}
+
+		if repair {
+			if err := txn.DeleteFrontier(frontier.Hash); err != nil {
+				return err
+			}
+			if ok {
+				if err := txn.AddFrontier(&block.Frontier{Address: address, Hash: frontier.Hash}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for hash, address := range state.frontiers {
+		if _, ok := stored[hash]; ok {
+			continue
+		}
+
+		report(CheckIssueFrontier, "frontier %s of %s is missing", hash, address)
+		if repair {
+			if err := txn.AddFrontier(&block.Frontier{Address: address, Hash: hash}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *Ledger) checkPending(txn StoreTxn, state *ledgerState, repair bool, report func(kind string, format string, a ...interface{})) error {
+	stored := map[pendingKey]*Pending{}
+	err := txn.WalkAllPending(func(destination nano.Address, hash block.Hash, pending *Pending) error {
+		stored[pendingKey{destination: destination, hash: hash}] = pending
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, pending := range stored {
+		expected, ok := state.pending[key]
+		if ok && *expected == *pending {
+			continue
+		}
+
+		if ok {
+			report(CheckIssuePending, "pending transaction %s for %s is %s from %s in %s, expected %s from %s in %s",
+				key.hash, key.destination, pending.Amount, pending.Address, pending.Epoch, expected.Amount, expected.Address, expected.Epoch)
+		} else {
+			report(CheckIssuePending, "block %s is not pending for %s", key.hash, key.destination)
+		}
+
+		if repair {
+			if err := txn.DeletePending(key.destination, key.hash); err != nil {
+				return err
+			}
+			if ok {
+				if err := txn.AddPending(key.destination, key.hash, expected); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for key, expected := range state.pending {
+		if _, ok := stored[key]; ok {
+			continue
+		}
+
+		report(CheckIssuePending, "pending transaction %s for %s is missing", key.hash, key.destination)
+		if repair {
+			if err := txn.AddPending(key.destination, key.hash, expected); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *Ledger) checkWeights(txn StoreTxn, state *ledgerState, repair bool, report func(kind string, format string, a ...interface{})) error {
+	stored := map[nano.Address]nano.Balance{}
+	err := txn.WalkRepresentations(func(address nano.Address, amount nano.Balance) error {
+		stored[address] = amount
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	reps := map[nano.Address]struct{}{}
+	for address := range stored {
+		reps[address] = struct{}{}
+	}
+	for address := range state.weights {
+		reps[address] = struct{}{}
+	}
+
+	for address := range reps {
+		weight, expected := stored[address], state.weights[address]
+		if weight.Equal(expected) {
+			continue
+		}
+
+		report(CheckIssueWeight, "weight of %s is %s, expected %s", address, weight, expected)
+		if repair {
+			if err := txn.SubRepresentation(address, weight); err != nil {
+				return err
+			}
+			if err := txn.AddRepresentation(address, expected); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// computeBalance returns the balance of the account after the block with the
+// given hash, using nothing but the blocks in the store. The balances that are
+// calculated along the way are cached in the given map.
+func (l *Ledger) computeBalance(txn StoreTxn, hash block.Hash, balances map[block.Hash]nano.Balance) (nano.Balance, error) {
+	if balance, ok := balances[hash]; ok {
+		return balance, nil
+	}
+
+	if hash == l.opts.Genesis.Block.Hash() {
+		return l.opts.Genesis.Balance, nil
+	}
+
+	blk, err := txn.GetBlock(hash)
+	if err != nil {
+		return nano.ZeroBalance, err
+	}
+
+	var balance nano.Balance
+	switch b := blk.(type) {
+	case *block.SendBlock:
+		balance = b.Balance
+	case *block.StateBlock:
+		balance = b.Balance
+	case *block.ChangeBlock:
+		if balance, err = l.computeBalance(txn, b.PreviousHash, balances); err != nil {
+			return nano.ZeroBalance, err
+		}
+	case *block.OpenBlock:
+		if balance, err = l.computeAmount(txn, b.SourceHash, balances); err != nil {
+			return nano.ZeroBalance, err
+		}
+	case *block.ReceiveBlock:
+		amount, err := l.computeAmount(txn, b.SourceHash, balances)
+		if err != nil {
+			return nano.ZeroBalance, err
+		}
+
+		prevBalance, err := l.computeBalance(txn, b.PreviousHash, balances)
+		if err != nil {
+			return nano.ZeroBalance, err
+		}
+		balance = prevBalance.Add(amount)
+	default:
+		return nano.ZeroBalance, block.ErrBadBlockType
+	}
+
+	balances[hash] = balance
+	return balance, nil
+}
+
+// computeAmount returns the amount that was sent by the send block with the
+// given hash, using nothing but the blocks in the store.
+func (l *Ledger) computeAmount(txn StoreTxn, hash block.Hash, balances map[block.Hash]nano.Balance) (nano.Balance, error) {
+	blk, err := txn.GetBlock(hash)
+	if err != nil {
+		return nano.ZeroBalance, err
+	}
+
+	balance, err := l.computeBalance(txn, hash, balances)
+	if err != nil {
+		return nano.ZeroBalance, err
+	}
+
+	prevBalance, err := l.computeBalance(txn, blk.Previous(), balances)
+	if err != nil {
+		return nano.ZeroBalance, err
+	}
+
+	return prevBalance.Sub(balance), nil
+}
+
+// computeEpoch returns the epoch of the account after the block with the given
+// hash, using nothing but the blocks in the store. The epochs and balances that
+// are calculated along the way are cached in the given maps.
+func (l *Ledger) computeEpoch(txn StoreTxn, hash block.Hash, balances map[block.Hash]nano.Balance, epochs map[block.Hash]block.Epoch) (block.Epoch, error) {
+	if epoch, ok := epochs[hash]; ok {
+		return epoch, nil
+	}
+
+	blk, err := txn.GetBlock(hash)
+	if err != nil {
+		return block.Epoch0, err
+	}
+
+	// legacy blocks can only be added to accounts in the first epoch
+	b, ok := blk.(*block.StateBlock)
+	if !ok {
+		return block.Epoch0, nil
+	}
+
+	epoch := block.Epoch0
+	prevBalance := nano.ZeroBalance
+	if !b.IsOpen() {
+		if epoch, err = l.computeEpoch(txn, b.PreviousHash, balances, epochs); err != nil {
+			return block.Epoch0, err
+		}
+		if prevBalance, err = l.computeBalance(txn, b.PreviousHash, balances); err != nil {
+			return block.Epoch0, err
+		}
+	}
+
+	switch b.Balance.Compare(prevBalance) {
+	case nano.BalanceCompBigger:
+		// receiving funds from an upgraded account upgrades the account
+		sourceEpoch, err := l.computeEpoch(txn, b.Link, balances, epochs)
+		if err != nil {
+			return block.Epoch0, err
+		}
+		if sourceEpoch > epoch {
+			epoch = sourceEpoch
+		}
+	case nano.BalanceCompEqual:
+		if linkEpoch, ok := l.opts.Genesis.EpochOf(b.Link); ok {
+			epoch = linkEpoch
+		}
+	}
+
+	epochs[hash] = epoch
+	return epoch, nil
+}
@@ -0,0 +1,211 @@
+package store
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// keyIndexMaxLevel is the maximum height of the skip list of a keyIndex. With
+// every level having a quarter of the keys of the level below it, this is
+// plenty for any amount of keys that fits in memory.
+const keyIndexMaxLevel = 16
+
+// kvTable is the committed state of a store that a cowTxn is built on.
+type kvTable interface {
+	// lookup returns the item with the given key or ErrNotFound.
+	lookup(key string) (*kvItem, error)
+	contains(key string) bool
+	// seek returns the node of the first key in the table that is equal to or
+	// greater than the given key. The keys that follow it can be visited in
+	// ascending order through the node.
+	seek(key string) *keyNode
+}
+
+// cowTxn is a copy-on-write transaction on a kvTable. Changes are kept in
+// writes until the store applies them to the table on commit, so discarding
+// the transaction rolls them back. A nil item marks a deleted key.
+type cowTxn struct {
+	table  kvTable
+	writes map[string]*kvItem
+}
+
+// keyIndex is a sorted set of keys, kept in a skip list so that keys can be
+// inserted and deleted in O(log n).
+type keyIndex struct {
+	head keyNode
+	rand *rand.Rand
+}
+
+type keyNode struct {
+	key  string
+	next []*keyNode
+}
+
+func newCowTxn(table kvTable) *cowTxn {
+	return &cowTxn{table: table, writes: map[string]*kvItem{}}
+}
+
+func (t *cowTxn) lookup(key string) (*kvItem, error) {
+	if item, ok := t.writes[key]; ok {
+		if item == nil {
+			return nil, ErrNotFound
+		}
+		return item, nil
+	}
+
+	return t.table.lookup(key)
+}
+
+func (t *cowTxn) get(key []byte) (*kvItem, error) {
+	item, err := t.lookup(string(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return copyItem(item), nil
+}
+
+func (t *cowTxn) set(key []byte, value []byte, meta byte) error {
+	t.writes[string(key)] = copyItem(&kvItem{key: key, value: value, meta: meta})
+	return nil
+}
+
+func (t *cowTxn) delete(key []byte) error {
+	t.writes[string(key)] = nil
+	return nil
+}
+
+func (t *cowTxn) iterate(prefix []byte, values bool, visit func(item *kvItem) error) error {
+	return t.seek(prefix, prefix, values, visit)
+}
+
+func (t *cowTxn) seek(prefix []byte, start []byte, values bool, visit func(item *kvItem) error) error {
+	if bytes.Compare(start, prefix) < 0 {
+		start = prefix
+	}
+
+	// the keys that were added in this transaction are merged with the keys
+	// in the table
+	var added []string
+	for key := range t.writes {
+		if !t.table.contains(key) && strings.HasPrefix(key, string(prefix)) && key >= string(start) {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+
+	node := t.table.seek(string(start))
+	for {
+		var key string
+		if node != nil && strings.HasPrefix(node.key, string(prefix)) && (len(added) == 0 || node.key < added[0]) {
+			key = node.key
+			node = node.next[0]
+		} else if len(added) > 0 {
+			key = added[0]
+			added = added[1:]
+		} else {
+			break
+		}
+
+		// the visit function may have deleted the key
+		item, err := t.lookup(key)
+		if err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+			return err
+		}
+
+		if err := visit(copyItem(item)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flush does nothing, the transaction is only applied to the table on commit.
+func (t *cowTxn) flush() error {
+	return nil
+}
+
+// newKeyIndex creates an empty index.
+func newKeyIndex() *keyIndex {
+	return &keyIndex{
+		head: keyNode{next: make([]*keyNode, keyIndexMaxLevel)},
+		rand: rand.New(rand.NewSource(1)),
+	}
+}
+
+// search returns the last node at every level whose key is smaller than the
+// given key.
+func (k *keyIndex) search(key string) []*keyNode {
+	prev := make([]*keyNode, keyIndexMaxLevel)
+	node := &k.head
+	for i := keyIndexMaxLevel - 1; i >= 0; i-- {
+		for node.next[i] != nil && node.next[i].key < key {
+			node = node.next[i]
+		}
+		prev[i] = node
+	}
+
+	return prev
+}
+
+// insert adds the given key to the index if it's not in there yet.
+func (k *keyIndex) insert(key string) {
+	prev := k.search(key)
+	if next := prev[0].next[0]; next != nil && next.key == key {
+		return
+	}
+
+	level := 1
+	for level < keyIndexMaxLevel && k.rand.Intn(4) == 0 {
+		level++
+	}
+
+	node := &keyNode{key: key, next: make([]*keyNode, level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = prev[i].next[i]
+		prev[i].next[i] = node
+	}
+}
+
+// delete removes the given key from the index, if it's in there.
+func (k *keyIndex) delete(key string) {
+	prev := k.search(key)
+	node := prev[0].next[0]
+	if node == nil || node.key != key {
+		return
+	}
+
+	for i := range node.next {
+		prev[i].next[i] = node.next[i]
+	}
+}
+
+// seek returns the node of the first key that is equal to or greater than the
+// given key, or nil if there is no such key.
+func (k *keyIndex) seek(key string) *keyNode {
+	return k.search(key)[0].next[0]
+}
+
+// prefix returns the keys that have the given prefix, in ascending order.
+func (k *keyIndex) prefix(prefix string) []string {
+	var keys []string
+	for node := k.seek(prefix); node != nil && strings.HasPrefix(node.key, prefix); node = node.next[0] {
+		keys = append(keys, node.key)
+	}
+
+	return keys
+}
+
+func copyItem(item *kvItem) *kvItem {
+	return &kvItem{
+		key:   append([]byte(nil), item.key...),
+		value: append([]byte(nil), item.value...),
+		meta:  item.meta,
+	}
+}
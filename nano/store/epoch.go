@@ -0,0 +1,172 @@
+package store
+
+import (
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+// epochOf reports whether the given state block is an epoch block and returns
+// the epoch it upgrades the account to. Blocks with the link of an epoch that
+// change the balance of the account are regular sends to that link. The
+// previous block must be in the ledger.
+func (l *Ledger) epochOf(txn StoreTxn, blk *block.StateBlock) (block.Epoch, bool, error) {
+	epoch, ok := l.opts.Genesis.EpochOf(blk.Link)
+	if !ok {
+		return block.Epoch0, false, nil
+	}
+
+	// the balance of epoch open blocks is checked in addEpochBlock
+	if blk.IsOpen() {
+		return epoch, true, nil
+	}
+
+	prevBalance, err := l.blockBalance(txn, blk.PreviousHash)
+	if err != nil {
+		return block.Epoch0, false, err
+	}
+
+	return epoch, blk.Balance.Equal(prevBalance), nil
+}
+
+// addEpochBlock upgrades the account of the given block to the given epoch. If
+// the account doesn't exist yet, it's opened with a zero balance, so that its
+// owner can receive funds that were sent to it by upgraded accounts.
+func (l *Ledger) addEpochBlock(txn StoreTxn, blk *block.StateBlock, epoch block.Epoch) error {
+	hash := blk.Hash()
+
+	// make sure the block was signed by the signer of the epoch
+	signer := l.opts.Genesis.Epochs[epoch].Signer
+	if !signer.Verify(hash[:], blk.Signature[:]) {
+		return ErrBadSignature
+	}
+
+	info, err := txn.GetAddress(blk.Address)
+	if err != nil {
+		if err != ErrNotFound {
+			return err
+		}
+
+		return l.addEpochOpenBlock(txn, blk, epoch)
+	}
+
+	// make sure the previous block is the head of this account
+	if blk.IsOpen() {
+		return ErrFork
+	}
+	if info.HeadBlock != blk.PreviousHash {
+		address, err := l.blockAccount(txn, blk.PreviousHash)
+		if err != nil {
+			return err
+		}
+		if address != blk.Address {
+			return ErrBlockPosition
+		}
+		return ErrFork
+	}
+
+	// accounts are upgraded one epoch at a time
+	if epoch != info.Epoch+1 {
+		return ErrEpochPosition
+	}
+
+	// the balance was already compared in epochOf, but the representative
+	// can't change either
+	rep, err := l.getRepresentative(txn, blk.Address)
+	if err != nil {
+		return err
+	}
+	if blk.Representative != rep {
+		return ErrRepresentativeMismatch
+	}
+
+	// update the address info
+	info.HeadBlock = hash
+	info.RepBlock = hash
+	info.Epoch = epoch
+	if err := txn.UpdateAddress(blk.Address, info); err != nil {
+		return err
+	}
+
+	// update the frontier of this account
+	if err := txn.DeleteFrontier(blk.PreviousHash); err != nil {
+		return err
+	}
+	frontier := block.Frontier{
+		Address: blk.Address,
+		Hash:    hash,
+	}
+	if err := txn.AddFrontier(&frontier); err != nil {
+		return err
+	}
+
+	// finally, add the block
+	return txn.AddBlock(blk)
+}
+
+func (l *Ledger) addEpochOpenBlock(txn StoreTxn, blk *block.StateBlock, epoch block.Epoch) error {
+	hash := blk.Hash()
+
+	// the previous block belongs to another account
+	if !blk.IsOpen() {
+		return ErrBlockPosition
+	}
+
+	// the burn account can't be opened
+	if blk.Address == (nano.Address{}) {
+		return ErrOpenedBurnAccount
+	}
+
+	// the pending funds are left for the owner of the account to receive
+	if !blk.Balance.Equal(nano.ZeroBalance) {
+		return ErrBalanceMismatch
+	}
+	if blk.Representative != (nano.Address{}) {
+		return ErrRepresentativeMismatch
+	}
+
+	// only accounts with something to receive can be opened
+	found, err := l.hasPending(txn, blk.Address)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrGapEpochOpenPending
+	}
+
+	// add address info
+	info := AddressInfo{
+		HeadBlock: hash,
+		RepBlock:  hash,
+		OpenBlock: hash,
+		Epoch:     epoch,
+	}
+	if err := txn.AddAddress(blk.Address, &info); err != nil {
+		return err
+	}
+
+	// add a frontier for this address
+	frontier := block.Frontier{
+		Address: blk.Address,
+		Hash:    hash,
+	}
+	if err := txn.AddFrontier(&frontier); err != nil {
+		return err
+	}
+
+	// finally, add the block
+	return txn.AddBlock(blk)
+}
+
+// hasPending reports whether the given address has any pending transactions.
+func (l *Ledger) hasPending(txn StoreTxn, address nano.Address) (bool, error) {
+	var found bool
+	err := txn.WalkPending(address, func(hash block.Hash, pending *Pending) error {
+		found = true
+		return errStopIteration
+	})
+	if err != nil && err != errStopIteration {
+		return false, err
+	}
+
+	return found, nil
+}
@@ -0,0 +1,500 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	fileStoreLogName     = "store.log"
+	fileStoreCompactName = "store.log.compact"
+	fileStoreMagic       = "GONANODB"
+
+	fileRecordHeaderSize = 8
+	fileOpSet            = 1
+	fileOpDelete         = 2
+
+	// the log is compacted once it's at least this large and more than half
+	// of it is garbage
+	fileCompactMinSize = 64 << 20
+	// the maximum size of the records written during compaction
+	fileCompactRecordSize = 4 << 20
+	// the approximate amount of bytes a set operation adds to a record besides
+	// the key and value
+	fileOpOverhead = 8
+)
+
+var (
+	ErrBadFileStore     = errors.New("not a gonano file store")
+	ErrCorruptFileStore = errors.New("file store is corrupt")
+
+	fileCRCTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// FileStore represents a Nano block lattice store that is backed by an
+// append-only log file. Every Update transaction is appended to the log as a
+// single checksummed record and synced to disk before it's applied, so a
+// transaction is either committed as a whole or not at all. An incomplete
+// record at the end of the log, left behind by a crash, is discarded when the
+// store is opened.
+//
+// The keys and the location of the values in the log are kept in memory. The
+// log is compacted when it's opened and whenever most of it consists of
+// overwritten values.
+type FileStore struct {
+	mutex sync.RWMutex
+	dir   string
+	file  *os.File
+	size  int64
+	table *fileTable
+}
+
+// fileEntry is the location of a value in the log.
+type fileEntry struct {
+	offset int64
+	size   uint32
+	meta   byte
+}
+
+// fileTable is the index of the values in the log.
+type fileTable struct {
+	file    *os.File
+	entries map[string]fileEntry
+	keys    *keyIndex
+	// live is the approximate amount of bytes in the log that are not garbage
+	live int64
+}
+
+// NewFileStore initializes/opens a file store in the given directory.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	// an interrupted compaction leaves the original log intact
+	if err := os.Remove(filepath.Join(dir, fileStoreCompactName)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	s := &FileStore{dir: dir}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	if s.needsCompaction() {
+		if err := s.compact(); err != nil {
+			s.file.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Close closes the log file.
+func (s *FileStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *FileStore) View(fn func(txn StoreTxn) error) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return fn(&kvStoreTxn{kv: newCowTxn(s.table)})
+}
+
+func (s *FileStore) Update(fn func(txn StoreTxn) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t := newCowTxn(s.table)
+	if err := fn(&kvStoreTxn{kv: t}); err != nil {
+		return err
+	}
+
+	if err := s.commit(t.writes); err != nil {
+		return err
+	}
+
+	if s.needsCompaction() {
+		return s.compact()
+	}
+
+	return nil
+}
+
+// Compact rewrites the log so that it only contains the current values.
+func (s *FileStore) Compact() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.compact()
+}
+
+func (s *FileStore) logPath() string {
+	return filepath.Join(s.dir, fileStoreLogName)
+}
+
+// open opens the log file and builds the index by replaying all records.
+func (s *FileStore) open() error {
+	file, err := os.OpenFile(s.logPath(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if stat.Size() == 0 {
+		if _, err := file.Write([]byte(fileStoreMagic)); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	table := &fileTable{file: file, entries: map[string]fileEntry{}, keys: newKeyIndex()}
+	size, err := table.load()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	// discard the incomplete record at the end of the log, if any
+	if size != stat.Size() && stat.Size() != 0 {
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	s.file = file
+	s.size = size
+	s.table = table
+	return nil
+}
+
+// commit appends the given changes to the log as a single record and applies
+// them to the index.
+func (s *FileStore) commit(writes map[string]*kvItem) error {
+	if len(writes) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(writes))
+	for key := range writes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	items := make([]*kvItem, len(keys))
+	for i, key := range keys {
+		if item := writes[key]; item != nil {
+			items[i] = item
+		} else {
+			items[i] = &kvItem{key: []byte(key)}
+		}
+	}
+
+	record, err := encodeFileRecord(items, func(i int) bool {
+		return writes[keys[i]] != nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.file.WriteAt(record, s.size); err != nil {
+		s.file.Truncate(s.size)
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		s.file.Truncate(s.size)
+		return err
+	}
+
+	if err := s.table.apply(record[fileRecordHeaderSize:], s.size+fileRecordHeaderSize); err != nil {
+		return err
+	}
+
+	s.size += int64(len(record))
+	return nil
+}
+
+func (s *FileStore) needsCompaction() bool {
+	return s.size >= fileCompactMinSize && s.size-s.table.live > s.size/2
+}
+
+// compact writes the current values to a new log file and atomically replaces
+// the old log with it.
+func (s *FileStore) compact() error {
+	path := filepath.Join(s.dir, fileStoreCompactName)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if _, err := writer.WriteString(fileStoreMagic); err != nil {
+		return err
+	}
+
+	var items []*kvItem
+	var size int
+	writeRecord := func() error {
+		record, err := encodeFileRecord(items, func(int) bool { return true })
+		if err != nil {
+			return err
+		}
+
+		items = nil
+		size = 0
+		_, err = writer.Write(record)
+		return err
+	}
+
+	for _, key := range s.table.keys.prefix("") {
+		item, err := s.table.lookup(key)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		size += len(item.key) + len(item.value) + fileOpOverhead
+		if size >= fileCompactRecordSize {
+			if err := writeRecord(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(items) > 0 {
+		if err := writeRecord(); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(path, s.logPath()); err != nil {
+		return err
+	}
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
+
+// encodeFileRecord encodes the given items as a log record. The items for
+// which set returns false are encoded as deletions.
+func encodeFileRecord(items []*kvItem, set func(i int) bool) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, fileRecordHeaderSize))
+
+	var varint [binary.MaxVarintLen64]byte
+	for i, item := range items {
+		if !set(i) {
+			buf.WriteByte(fileOpDelete)
+			buf.Write(varint[:binary.PutUvarint(varint[:], uint64(len(item.key)))])
+			buf.Write(item.key)
+			continue
+		}
+
+		buf.WriteByte(fileOpSet)
+		buf.Write(varint[:binary.PutUvarint(varint[:], uint64(len(item.key)))])
+		buf.Write(item.key)
+		buf.WriteByte(item.meta)
+		buf.Write(varint[:binary.PutUvarint(varint[:], uint64(len(item.value)))])
+		buf.Write(item.value)
+	}
+
+	record := buf.Bytes()
+	payload := record[fileRecordHeaderSize:]
+	if len(payload) > math.MaxUint32 {
+		return nil, errors.New("transaction too large for the file store")
+	}
+
+	binary.LittleEndian.PutUint32(record[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:], crc32.Checksum(payload, fileCRCTable))
+	return record, nil
+}
+
+// load replays the records in the log and returns the offset of the end of the
+// last complete record.
+func (t *fileTable) load() (int64, error) {
+	stat, err := t.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(t.file, 0, stat.Size()))
+
+	magic := make([]byte, len(fileStoreMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != fileStoreMagic {
+		return 0, ErrBadFileStore
+	}
+
+	offset := int64(len(magic))
+	for {
+		var header [fileRecordHeaderSize]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return offset, nil
+			}
+			return 0, err
+		}
+
+		size := int64(binary.LittleEndian.Uint32(header[0:]))
+		end := offset + fileRecordHeaderSize + size
+		if end > stat.Size() {
+			// the record was not written completely
+			return offset, nil
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return 0, err
+		}
+
+		if crc32.Checksum(payload, fileCRCTable) != binary.LittleEndian.Uint32(header[4:]) {
+			// only the last record may have been torn by a crash
+			if end == stat.Size() {
+				return offset, nil
+			}
+			return 0, ErrCorruptFileStore
+		}
+
+		if err := t.apply(payload, offset+fileRecordHeaderSize); err != nil {
+			return 0, err
+		}
+
+		offset = end
+	}
+}
+
+// apply updates the index with the operations in the given record payload,
+// which is located at the given offset in the log.
+func (t *fileTable) apply(payload []byte, offset int64) error {
+	reader := bytes.NewReader(payload)
+	pos := func() int64 {
+		return offset + int64(len(payload)-reader.Len())
+	}
+
+	for reader.Len() > 0 {
+		op, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		keySize, err := binary.ReadUvarint(reader)
+		if err != nil || keySize > uint64(reader.Len()) {
+			return ErrCorruptFileStore
+		}
+		key := make([]byte, keySize)
+		reader.Read(key)
+
+		switch op {
+		case fileOpSet:
+			meta, err := reader.ReadByte()
+			if err != nil {
+				return ErrCorruptFileStore
+			}
+
+			valueSize, err := binary.ReadUvarint(reader)
+			if err != nil || valueSize > uint64(reader.Len()) {
+				return ErrCorruptFileStore
+			}
+
+			t.set(string(key), fileEntry{offset: pos(), size: uint32(valueSize), meta: meta})
+			reader.Seek(int64(valueSize), io.SeekCurrent)
+		case fileOpDelete:
+			t.delete(string(key))
+		default:
+			return ErrCorruptFileStore
+		}
+	}
+
+	return nil
+}
+
+func (t *fileTable) set(key string, entry fileEntry) {
+	if old, ok := t.entries[key]; ok {
+		t.live -= int64(len(key)) + int64(old.size) + fileOpOverhead
+	}
+
+	t.entries[key] = entry
+	t.keys.insert(key)
+	t.live += int64(len(key)) + int64(entry.size) + fileOpOverhead
+}
+
+func (t *fileTable) delete(key string) {
+	old, ok := t.entries[key]
+	if !ok {
+		return
+	}
+
+	t.live -= int64(len(key)) + int64(old.size) + fileOpOverhead
+	delete(t.entries, key)
+	t.keys.delete(key)
+}
+
+func (t *fileTable) lookup(key string) (*kvItem, error) {
+	entry, ok := t.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	value := make([]byte, entry.size)
+	if _, err := t.file.ReadAt(value, entry.offset); err != nil {
+		return nil, err
+	}
+
+	return &kvItem{key: []byte(key), value: value, meta: entry.meta}, nil
+}
+
+func (t *fileTable) contains(key string) bool {
+	_, ok := t.entries[key]
+	return ok
+}
+
+func (t *fileTable) seek(key string) *keyNode {
+	return t.keys.seek(key)
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+func TestFileStoreReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gonano_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := []block.Block{generateBlock(t), generateBlock(t), generateBlock(t)}
+	for _, blk := range blocks {
+		blk := blk
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			return txn.AddBlock(blk)
+		})
+	}
+	mustUpdate(t, store, func(txn StoreTxn) error {
+		return txn.DeleteBlock(blocks[0].Hash())
+	})
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a crash while writing a transaction
+	logPath := filepath.Join(dir, fileStoreLogName)
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte{0xff, 0x00, 0x00, 0x00, 0x01, 0x02}); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	assertBlocks := func(store Store) {
+		mustView(t, store, func(txn StoreTxn) error {
+			if count, err := txn.CountBlocks(); err != nil || count != 2 {
+				t.Fatalf("unexpected block count: %d, %v", count, err)
+			}
+			for _, blk := range blocks[1:] {
+				res, err := txn.GetBlock(blk.Hash())
+				if err != nil {
+					return err
+				}
+				if res.Hash() != blk.Hash() {
+					t.Fatalf("unexpected block: %s", res.Hash())
+				}
+			}
+			return nil
+		})
+	}
+
+	store, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBlocks(store)
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	assertBlocks(store)
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	assertBlocks(store)
+}
+
+func TestKeyIndex(t *testing.T) {
+	index := newKeyIndex()
+	for _, key := range []string{"f", "b", "d"} {
+		index.insert(key)
+	}
+
+	tests := []struct {
+		set     []string
+		deleted []string
+		keys    []string
+	}{
+		{set: []string{"b", "f"}, keys: []string{"b", "d", "f"}},
+		{set: []string{"a", "e", "g"}, keys: []string{"a", "b", "d", "e", "f", "g"}},
+		{set: []string{"c"}, deleted: []string{"a", "g", "x"}, keys: []string{"b", "c", "d", "e", "f"}},
+		{deleted: []string{"b", "c", "d", "e", "f"}, keys: nil},
+	}
+
+	for i, test := range tests {
+		for _, key := range test.set {
+			index.insert(key)
+		}
+		for _, key := range test.deleted {
+			index.delete(key)
+		}
+
+		if keys := index.prefix(""); !reflect.DeepEqual(keys, test.keys) {
+			t.Fatalf("unexpected keys after update %d: %v", i, keys)
+		}
+	}
+
+	// a large amount of keys stays sorted and can be looked up by prefix
+	for i := 999; i >= 0; i-- {
+		index.insert(fmt.Sprintf("%04d", i))
+	}
+	for i := 0; i < 1000; i += 2 {
+		index.delete(fmt.Sprintf("%04d", i))
+	}
+	keys := index.prefix("")
+	if len(keys) != 500 || !sort.StringsAreSorted(keys) {
+		t.Fatalf("unexpected keys: %d, sorted: %t", len(keys), sort.StringsAreSorted(keys))
+	}
+	if keys := index.prefix("09"); len(keys) != 50 || keys[0] != "0901" {
+		t.Fatalf("unexpected keys with prefix: %v", keys)
+	}
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+var (
+	ErrNoFork = errors.New("no fork was recorded for the given root")
+)
+
+// ForkRoot returns the root that is shared by competing blocks: the account
+// for open blocks and the previous block for all other blocks.
+func ForkRoot(blk block.Block) block.Hash {
+	switch b := blk.(type) {
+	case *block.OpenBlock:
+		return block.Hash(b.Address)
+	case *block.StateBlock:
+		if b.IsOpen() {
+			return block.Hash(b.Address)
+		}
+	}
+
+	return blk.Previous()
+}
+
+// Forks returns all competing blocks for the given root. The block that is
+// currently in the ledger (if any) comes first.
+func (l *Ledger) Forks(root block.Hash) ([]block.Block, error) {
+	var blocks []block.Block
+
+	err := l.db.View(func(txn StoreTxn) error {
+		forks, err := txn.GetForks(root)
+		if err != nil {
+			return err
+		}
+		if len(forks) == 0 {
+			return ErrNoFork
+		}
+
+		current, err := l.successor(txn, forks[0])
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+		if err == nil {
+			blk, err := txn.GetBlock(current)
+			if err != nil {
+				return err
+			}
+			blocks = append(blocks, blk)
+		}
+
+		blocks = append(blocks, forks...)
+		return nil
+	})
+
+	return blocks, err
+}
+
+// Tally sums up the voting weight of the representatives in the given list of
+// votes for every block they voted for.
+func (l *Ledger) Tally(votes map[nano.Address]block.Hash) (map[block.Hash]nano.Balance, error) {
+	var tally map[block.Hash]nano.Balance
+
+	err := l.db.View(func(txn StoreTxn) (err error) {
+		tally, err = l.tally(txn, votes)
+		return err
+	})
+
+	return tally, err
+}
+
+// ResolveFork resolves the fork for the given root using the given votes of
+// representatives. The block with the most voting weight wins. If the winner
+// is not the block that is currently in the ledger, the current block is
+// rolled back and replaced with the winner. In case of a tie, the ledger is
+// left untouched. The hash of the winning block is returned.
+func (l *Ledger) ResolveFork(root block.Hash, votes map[nano.Address]block.Hash) (block.Hash, error) {
+	var winner block.Hash
+
+	err := l.db.Update(func(txn StoreTxn) error {
+		forks, err := txn.GetForks(root)
+		if err != nil {
+			return err
+		}
+		if len(forks) == 0 {
+			return ErrNoFork
+		}
+
+		current, err := l.successor(txn, forks[0])
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+
+		tally, err := l.tally(txn, votes)
+		if err != nil {
+			return err
+		}
+
+		var winnerBlock block.Block
+		winner = current
+		weight := tally[current]
+		for _, blk := range forks {
+			hash := blk.Hash()
+			if tally[hash].Compare(weight) == nano.BalanceCompBigger {
+				winner = hash
+				winnerBlock = blk
+				weight = tally[hash]
+			}
+		}
+
+		if winnerBlock != nil {
+			if !current.IsZero() {
+				if err := l.rollback(txn, current); err != nil {
+					return err
+				}
+			}
+
+			// abort the whole transaction, so that the rollback is undone,
+			// if the winner can't be added to the ledger
+			res, err := l.processBlock(txn, winnerBlock)
+			if err != nil {
+				return err
+			}
+			if res != ProcessProgress {
+				return fmt.Errorf("winning block %s was rejected: %s", winner, res)
+			}
+		}
+
+		return txn.DeleteForks(root)
+	})
+
+	return winner, err
+}
+
+func (l *Ledger) tally(txn StoreTxn, votes map[nano.Address]block.Hash) (map[block.Hash]nano.Balance, error) {
+	tally := map[block.Hash]nano.Balance{}
+
+	for rep, hash := range votes {
+		weight, err := txn.GetRepresentation(rep)
+		if err != nil {
+			return nil, err
+		}
+
+		tally[hash] = tally[hash].Add(weight)
+	}
+
+	return tally, nil
+}
+
+func (l *Ledger) addFork(txn StoreTxn, blk block.Block) error {
+	err := txn.AddFork(ForkRoot(blk), blk)
+	if err == ErrBlockExists {
+		return nil
+	}
+
+	return err
+}
+
+// successor returns the hash of the block in the ledger that takes the place
+// on the account chain that the given block is competing for.
+func (l *Ledger) successor(txn StoreTxn, blk block.Block) (block.Hash, error) {
+	prevHash := blk.Previous()
+	if prevHash.IsZero() {
+		info, err := txn.GetAddress(nano.Address(ForkRoot(blk)))
+		if err != nil {
+			return block.Hash{}, err
+		}
+
+		return info.OpenBlock, nil
+	}
+
+	sideband, err := txn.GetSideband(prevHash)
+	if err != nil {
+		return block.Hash{}, err
+	}
+
+	if sideband.Successor.IsZero() {
+		return block.Hash{}, ErrNotFound
+	}
+
+	return sideband.Successor, nil
+}
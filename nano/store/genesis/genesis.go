@@ -10,12 +10,38 @@ import (
 )
 
 type Genesis struct {
-	Block         block.OpenBlock
-	Balance       nano.Balance
-	WorkThreshold uint64
+	Block   block.OpenBlock
+	Balance nano.Balance
+	// WorkThresholds decides the work threshold of the blocks on the network.
+	// The genesis block uses the base threshold.
+	WorkThresholds block.WorkThresholds
+	// Epochs holds the epochs the accounts on the network can be upgraded to.
+	Epochs map[block.Epoch]Epoch
+}
+
+// Epoch describes an epoch of the network.
+type Epoch struct {
+	// Link is the link of the state blocks that upgrade an account to this
+	// epoch.
+	Link block.Hash
+	// Signer is the address that signs the epoch blocks.
+	Signer nano.Address
 }
 
 var (
+	epochV1Link = epochLink("epoch v1 block")
+	epochV2Link = epochLink("epoch v2 block")
+
+	workThresholds = block.WorkThresholds{
+		Base: uint64(0xffffffc000000000),
+		Epochs: map[block.Epoch]block.EpochThresholds{
+			block.Epoch2: {
+				Send:    uint64(0xfffffff800000000),
+				Receive: uint64(0xfffffe0000000000),
+			},
+		},
+	}
+
 	Live = Genesis{
 		Block: block.OpenBlock{
 			SourceHash:     util.MustDecodeHex32("e89208dd038fbb269987689621d52292ae9c35941a7484756ecced92a65093ba"),
@@ -24,8 +50,18 @@ var (
 			Work:           0x62f05417dd3fb691,
 			Signature:      util.MustDecodeHex64("9f0c933c8ade004d808ea1985fa746a7e95ba2a38f867640f53ec8f180bdfe9e2c1268dead7c2664f356e37aba362bc58e46dba03e523a7b5a19e4b6eb12bb02"),
 		},
-		Balance:       nano.ParseBalanceInts(0xffffffffffffffff, 0xffffffffffffffff),
-		WorkThreshold: uint64(0xffffffc000000000),
+		Balance:        nano.ParseBalanceInts(0xffffffffffffffff, 0xffffffffffffffff),
+		WorkThresholds: workThresholds,
+		Epochs: map[block.Epoch]Epoch{
+			block.Epoch1: {
+				Link:   epochV1Link,
+				Signer: util.MustDecodeHex32("e89208dd038fbb269987689621d52292ae9c35941a7484756ecced92a65093ba"),
+			},
+			block.Epoch2: {
+				Link:   epochV2Link,
+				Signer: util.MustDecodeHex32("dd24a9200d4bf8247981e4ac63dbde38fd2319386970a26d02ecc98c79975db1"),
+			},
+		},
 	}
 
 	Beta = Genesis{
@@ -36,11 +72,41 @@ var (
 			Work:           0x000000000f0aaeeb,
 			Signature:      util.MustDecodeHex64("a726490e3325e4fa59c1c900d5b6eebb15fe13d99f49d475b93f0aacc5635929a0614cf3892764a04d1c6732a0d716ffeb254d4154c6f544d11e6630f201450b"),
 		},
-		Balance:       nano.ParseBalanceInts(0xffffffffffffffff, 0xffffffffffffffff),
-		WorkThreshold: uint64(0xffffffc000000000),
+		Balance:        nano.ParseBalanceInts(0xffffffffffffffff, 0xffffffffffffffff),
+		WorkThresholds: workThresholds,
+		Epochs: map[block.Epoch]Epoch{
+			block.Epoch1: {
+				Link:   epochV1Link,
+				Signer: util.MustDecodeHex32("a59a47cc4f593e75ae9ad653fda9358e2f7898d9acc8c60e80d0495ce20fba9f"),
+			},
+			block.Epoch2: {
+				Link:   epochV2Link,
+				Signer: util.MustDecodeHex32("a59a47cc4f593e75ae9ad653fda9358e2f7898d9acc8c60e80d0495ce20fba9f"),
+			},
+		},
 	}
 )
 
+// epochLink returns the link of the epoch blocks of an epoch: the given string,
+// padded with zeroes.
+func epochLink(s string) block.Hash {
+	var link block.Hash
+	copy(link[:], s)
+	return link
+}
+
+// EpochOf returns the epoch that state blocks with the given link upgrade
+// accounts to, if it's the link of an epoch.
+func (g *Genesis) EpochOf(link block.Hash) (block.Epoch, bool) {
+	for epoch, info := range g.Epochs {
+		if info.Link == link {
+			return epoch, true
+		}
+	}
+
+	return block.Epoch0, false
+}
+
 func Get(network proto.Network) (Genesis, error) {
 	switch network {
 	case proto.NetworkLive:
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+var (
+	ErrNotInChain = errors.New("block is not on the chain of the given account")
+)
+
+// HistoryEntry describes a block on an account chain.
+type HistoryEntry struct {
+	Hash    block.Hash
+	Type    byte
+	Subtype block.Subtype
+	// Account is the counterparty of the block: the destination of sends and
+	// the sender of receives. For change blocks, it's the new representative
+	// and for epoch blocks, it's the signer of the epoch.
+	Account nano.Address
+	// Amount is the amount that was sent or received.
+	Amount    nano.Balance
+	Balance   nano.Balance
+	Height    uint64
+	Timestamp int64
+}
+
+// AccountHistory returns up to count entries of the chain of the given address,
+// starting at the block with the given hash. If start is zero, the history
+// starts at the head block, or at the open block if reverse is set. Entries
+// are ordered from new to old, unless reverse is set. If count is zero, the
+// rest of the chain is returned.
+func (l *Ledger) AccountHistory(address nano.Address, start block.Hash, count int, reverse bool) ([]*HistoryEntry, error) {
+	var entries []*HistoryEntry
+
+	err := l.db.View(func(txn StoreTxn) error {
+		hash := start
+		if hash.IsZero() {
+			info, err := txn.GetAddress(address)
+			if err != nil {
+				return err
+			}
+
+			if reverse {
+				hash = info.OpenBlock
+			} else {
+				hash = info.HeadBlock
+			}
+		}
+
+		for !hash.IsZero() && (count == 0 || len(entries) < count) {
+			blk, err := txn.GetBlock(hash)
+			if err != nil {
+				return err
+			}
+
+			sideband, err := txn.GetSideband(hash)
+			if err != nil {
+				return err
+			}
+			if sideband.Account != address {
+				return ErrNotInChain
+			}
+
+			entry, err := l.historyEntry(txn, blk, sideband)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+
+			if reverse {
+				hash = sideband.Successor
+			} else {
+				hash = blk.Previous()
+			}
+		}
+
+		return nil
+	})
+
+	return entries, err
+}
+
+func (l *Ledger) historyEntry(txn StoreTxn, blk block.Block, sideband *Sideband) (*HistoryEntry, error) {
+	hash := blk.Hash()
+	entry := HistoryEntry{
+		Hash:      hash,
+		Type:      blk.ID(),
+		Balance:   sideband.Balance,
+		Height:    sideband.Height,
+		Timestamp: sideband.Timestamp,
+	}
+
+	prevBalance := nano.ZeroBalance
+	if prevHash := blk.Previous(); !prevHash.IsZero() {
+		prev, err := txn.GetSideband(prevHash)
+		if err != nil {
+			return nil, err
+		}
+		prevBalance = prev.Balance
+	}
+
+	var source block.Hash
+	switch b := blk.(type) {
+	case *block.SendBlock:
+		entry.Subtype = block.SubtypeSend
+		entry.Account = b.Destination
+	case *block.ReceiveBlock:
+		entry.Subtype = block.SubtypeReceive
+		source = b.SourceHash
+	case *block.OpenBlock:
+		entry.Subtype = block.SubtypeOpen
+		source = b.SourceHash
+	case *block.ChangeBlock:
+		entry.Subtype = block.SubtypeChange
+		entry.Account = b.Representative
+	case *block.StateBlock:
+		switch b.Balance.Compare(prevBalance) {
+		case nano.BalanceCompSmaller:
+			entry.Subtype = block.SubtypeSend
+			entry.Account = nano.Address(b.Link)
+		case nano.BalanceCompBigger:
+			entry.Subtype = block.SubtypeReceive
+			if b.IsOpen() {
+				entry.Subtype = block.SubtypeOpen
+			}
+			source = b.Link
+		default:
+			if epoch, ok := l.opts.Genesis.EpochOf(b.Link); ok {
+				entry.Subtype = block.SubtypeEpoch
+				entry.Account = l.opts.Genesis.Epochs[epoch].Signer
+			} else {
+				entry.Subtype = block.SubtypeChange
+				entry.Account = b.Representative
+			}
+		}
+	default:
+		return nil, block.ErrBadBlockType
+	}
+
+	if !source.IsZero() {
+		if hash == l.opts.Genesis.Block.Hash() {
+			// the source of the genesis block is not a block
+			entry.Account = l.opts.Genesis.Block.Address
+		} else {
+			sourceSideband, err := txn.GetSideband(source)
+			if err != nil {
+				return nil, err
+			}
+			entry.Account = sourceSideband.Account
+		}
+	}
+
+	if sideband.Balance.Compare(prevBalance) == nano.BalanceCompSmaller {
+		entry.Amount = prevBalance.Sub(sideband.Balance)
+	} else {
+		entry.Amount = sideband.Balance.Sub(prevBalance)
+	}
+
+	return &entry, nil
+}
@@ -0,0 +1,782 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+const (
+	idPrefixBlock byte = iota
+	idPrefixUncheckedBlockPrevious
+	idPrefixUncheckedBlockSource
+	idPrefixAddress
+	idPrefixFrontier
+	idPrefixPending
+	idPrefixRepresentation
+	idPrefixFork
+	idPrefixConfirmation
+	idPrefixVoteSequence
+	idPrefixSideband
+	idPrefixVersion
+)
+
+var (
+	// errStopIteration is used to stop iterating over the keys of a kvTxn
+	// early
+	errStopIteration = errors.New("stop iteration")
+)
+
+// kvItem is a key/value pair in a key/value store. Blocks store their type in
+// meta.
+type kvItem struct {
+	key   []byte
+	value []byte
+	meta  byte
+}
+
+// kvTxn is a transaction on an ordered key/value store. All store backends
+// implement it, so that they can share the key layout and encoding of kvStoreTxn.
+type kvTxn interface {
+	// get returns the item with the given key or ErrNotFound.
+	get(key []byte) (*kvItem, error)
+	set(key []byte, value []byte, meta byte) error
+	delete(key []byte) error
+	// iterate calls visit for every item that has the given prefix, in
+	// ascending order of the keys. If values is false, the values of the items
+	// may be left empty.
+	iterate(prefix []byte, values bool, visit func(item *kvItem) error) error
+	// seek is like iterate, but skips the items with a key smaller than start.
+	seek(prefix []byte, start []byte, values bool, visit func(item *kvItem) error) error
+	flush() error
+}
+
+// kvStoreTxn implements StoreTxn on top of a kvTxn.
+type kvStoreTxn struct {
+	kv kvTxn
+}
+
+func decodeBlock(item *kvItem) (block.Block, error) {
+	blk, err := block.New(item.meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := blk.UnmarshalBinary(item.value); err != nil {
+		return nil, err
+	}
+
+	return blk, nil
+}
+
+func (t *kvStoreTxn) has(key []byte) (bool, error) {
+	if _, err := t.kv.get(key); err != nil {
+		if err == ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (t *kvStoreTxn) count(prefix []byte) (uint64, error) {
+	var count uint64
+	err := t.kv.iterate(prefix, false, func(item *kvItem) error {
+		count++
+		return nil
+	})
+
+	return count, err
+}
+
+// Empty reports whether the database is empty or not.
+func (t *kvStoreTxn) Empty() (bool, error) {
+	empty := true
+	prefix := [...]byte{idPrefixBlock}
+	err := t.kv.iterate(prefix[:], false, func(item *kvItem) error {
+		empty = false
+		return errStopIteration
+	})
+	if err != nil && err != errStopIteration {
+		return false, err
+	}
+
+	return empty, nil
+}
+
+func (t *kvStoreTxn) Flush() error {
+	return t.kv.flush()
+}
+
+// GetVersion returns the schema version of the database. Databases that were
+// created before the version was recorded have version 0.
+func (t *kvStoreTxn) GetVersion() (uint64, error) {
+	key := [...]byte{idPrefixVersion}
+
+	item, err := t.kv.get(key[:])
+	if err != nil {
+		if err == ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(item.value), nil
+}
+
+// SetVersion sets the schema version of the database.
+func (t *kvStoreTxn) SetVersion(version uint64) error {
+	key := [...]byte{idPrefixVersion}
+
+	var versionBytes [8]byte
+	binary.LittleEndian.PutUint64(versionBytes[:], version)
+	return t.kv.set(key[:], versionBytes[:], 0)
+}
+
+// AddBlock adds the given block to the database.
+func (t *kvStoreTxn) AddBlock(blk block.Block) error {
+	hash := blk.Hash()
+	blockBytes, err := blk.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixBlock
+	copy(key[1:], hash[:])
+
+	// never overwrite implicitly
+	if _, err := t.kv.get(key[:]); err != nil && err != ErrNotFound {
+		return err
+	} else if err == nil {
+		return ErrBlockExists
+	}
+
+	return t.kv.set(key[:], blockBytes, blk.ID())
+}
+
+// GetBlock retrieves the block with the given hash from the database.
+func (t *kvStoreTxn) GetBlock(hash block.Hash) (block.Block, error) {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixBlock
+	copy(key[1:], hash[:])
+
+	item, err := t.kv.get(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeBlock(item)
+}
+
+func (t *kvStoreTxn) DeleteBlock(hash block.Hash) error {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixBlock
+	copy(key[1:], hash[:])
+	return t.kv.delete(key[:])
+}
+
+// HasBlock reports whether the database contains a block with the given hash.
+func (t *kvStoreTxn) HasBlock(hash block.Hash) (bool, error) {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixBlock
+	copy(key[1:], hash[:])
+	return t.has(key[:])
+}
+
+// WalkBlocks calls visit for every block in the database, ordered by hash.
+func (t *kvStoreTxn) WalkBlocks(visit BlockWalkFunc) error {
+	return t.SeekBlocks(block.Hash{}, visit)
+}
+
+// SeekBlocks calls visit for every block in the database with a hash equal to
+// or greater than start, ordered by hash.
+func (t *kvStoreTxn) SeekBlocks(start block.Hash, visit BlockWalkFunc) error {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixBlock
+	copy(key[1:], start[:])
+
+	return t.kv.seek(key[:1], key[:], true, func(item *kvItem) error {
+		blk, err := decodeBlock(item)
+		if err != nil {
+			return err
+		}
+
+		return visit(blk)
+	})
+}
+
+// CountBlocks returns the total amount of blocks in the database.
+func (t *kvStoreTxn) CountBlocks() (uint64, error) {
+	prefix := [...]byte{idPrefixBlock}
+	return t.count(prefix[:])
+}
+
+func (t *kvStoreTxn) AddSideband(hash block.Hash, sideband *Sideband) error {
+	sidebandBytes, err := sideband.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixSideband
+	copy(key[1:], hash[:])
+
+	// never overwrite implicitly
+	if _, err := t.kv.get(key[:]); err != nil && err != ErrNotFound {
+		return err
+	} else if err == nil {
+		return errors.New("sideband already exists")
+	}
+
+	return t.kv.set(key[:], sidebandBytes, 0)
+}
+
+func (t *kvStoreTxn) GetSideband(hash block.Hash) (*Sideband, error) {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixSideband
+	copy(key[1:], hash[:])
+
+	item, err := t.kv.get(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var sideband Sideband
+	if err := sideband.UnmarshalBinary(item.value); err != nil {
+		return nil, err
+	}
+
+	return &sideband, nil
+}
+
+func (t *kvStoreTxn) UpdateSideband(hash block.Hash, sideband *Sideband) error {
+	sidebandBytes, err := sideband.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixSideband
+	copy(key[1:], hash[:])
+
+	return t.kv.set(key[:], sidebandBytes, 0)
+}
+
+func (t *kvStoreTxn) DeleteSideband(hash block.Hash) error {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixSideband
+	copy(key[1:], hash[:])
+	return t.kv.delete(key[:])
+}
+
+// AddUncheckedBlock adds the given block to the database.
+func (t *kvStoreTxn) AddUncheckedBlock(parentHash block.Hash, blk block.Block, kind UncheckedKind) error {
+	blockBytes, err := blk.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var key [1 + block.HashSize]byte
+	key[0] = uncheckedKindToPrefix(kind)
+	copy(key[1:], parentHash[:])
+
+	// never overwrite implicitly
+	if _, err := t.kv.get(key[:]); err != nil && err != ErrNotFound {
+		return err
+	} else if err == nil {
+		return ErrBlockExists
+	}
+
+	return t.kv.set(key[:], blockBytes, blk.ID())
+}
+
+// GetUncheckedBlock retrieves the block with the given hash from the database.
+func (t *kvStoreTxn) GetUncheckedBlock(parentHash block.Hash, kind UncheckedKind) (block.Block, error) {
+	var key [1 + block.HashSize]byte
+	key[0] = uncheckedKindToPrefix(kind)
+	copy(key[1:], parentHash[:])
+
+	item, err := t.kv.get(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeBlock(item)
+}
+
+func (t *kvStoreTxn) DeleteUncheckedBlock(parentHash block.Hash, kind UncheckedKind) error {
+	var key [1 + block.HashSize]byte
+	key[0] = uncheckedKindToPrefix(kind)
+	copy(key[1:], parentHash[:])
+	return t.kv.delete(key[:])
+}
+
+// HasUncheckedBlock reports whether the database contains a block with the given hash.
+func (t *kvStoreTxn) HasUncheckedBlock(hash block.Hash, kind UncheckedKind) (bool, error) {
+	var key [1 + block.HashSize]byte
+	key[0] = uncheckedKindToPrefix(kind)
+	copy(key[1:], hash[:])
+	return t.has(key[:])
+}
+
+func (t *kvStoreTxn) walkUncheckedBlocks(kind UncheckedKind, visit UncheckedBlockWalkFunc) error {
+	prefix := [...]byte{uncheckedKindToPrefix(kind)}
+	return t.kv.iterate(prefix[:], true, func(item *kvItem) error {
+		blk, err := decodeBlock(item)
+		if err != nil {
+			return err
+		}
+
+		return visit(blk, kind)
+	})
+}
+
+func (t *kvStoreTxn) WalkUncheckedBlocks(visit UncheckedBlockWalkFunc) error {
+	var err error
+	if err = t.walkUncheckedBlocks(UncheckedKindPrevious, visit); err != nil {
+		return err
+	}
+
+	return t.walkUncheckedBlocks(UncheckedKindSource, visit)
+}
+
+func (t *kvStoreTxn) CountUncheckedBlocks() (uint64, error) {
+	var total uint64
+	for _, kind := range []UncheckedKind{UncheckedKindPrevious, UncheckedKindSource} {
+		prefix := [...]byte{uncheckedKindToPrefix(kind)}
+		count, err := t.count(prefix[:])
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+
+	return total, nil
+}
+
+func (t *kvStoreTxn) AddAddress(address nano.Address, info *AddressInfo) error {
+	infoBytes, err := info.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var key [1 + nano.AddressSize]byte
+	key[0] = idPrefixAddress
+	copy(key[1:], address[:])
+
+	// never overwrite implicitly
+	if _, err := t.kv.get(key[:]); err != nil && err != ErrNotFound {
+		return err
+	} else if err == nil {
+		return errors.New("address already exists")
+	}
+
+	return t.kv.set(key[:], infoBytes, 0)
+}
+
+func (t *kvStoreTxn) GetAddress(address nano.Address) (*AddressInfo, error) {
+	var key [1 + nano.AddressSize]byte
+	key[0] = idPrefixAddress
+	copy(key[1:], address[:])
+
+	item, err := t.kv.get(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var info AddressInfo
+	if err := info.UnmarshalBinary(item.value); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+func (t *kvStoreTxn) UpdateAddress(address nano.Address, info *AddressInfo) error {
+	infoBytes, err := info.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var key [1 + nano.AddressSize]byte
+	key[0] = idPrefixAddress
+	copy(key[1:], address[:])
+
+	return t.kv.set(key[:], infoBytes, 0)
+}
+
+func (t *kvStoreTxn) DeleteAddress(address nano.Address) error {
+	var key [1 + nano.AddressSize]byte
+	key[0] = idPrefixAddress
+	copy(key[1:], address[:])
+	return t.kv.delete(key[:])
+}
+
+func (t *kvStoreTxn) HasAddress(address nano.Address) (bool, error) {
+	var key [1 + nano.AddressSize]byte
+	key[0] = idPrefixAddress
+	copy(key[1:], address[:])
+	return t.has(key[:])
+}
+
+// WalkAddresses calls visit for every address in the database, ordered by
+// address.
+func (t *kvStoreTxn) WalkAddresses(visit AddressWalkFunc) error {
+	return t.SeekAddresses(nano.Address{}, visit)
+}
+
+// SeekAddresses calls visit for every address in the database that is equal to
+// or greater than start, ordered by address.
+func (t *kvStoreTxn) SeekAddresses(start nano.Address, visit AddressWalkFunc) error {
+	var key [1 + nano.AddressSize]byte
+	key[0] = idPrefixAddress
+	copy(key[1:], start[:])
+
+	return t.kv.seek(key[:1], key[:], true, func(item *kvItem) error {
+		var info AddressInfo
+		if err := info.UnmarshalBinary(item.value); err != nil {
+			return err
+		}
+
+		var address nano.Address
+		copy(address[:], item.key[1:])
+
+		return visit(address, &info)
+	})
+}
+
+func (t *kvStoreTxn) AddFrontier(frontier *block.Frontier) error {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixFrontier
+	copy(key[1:], frontier.Hash[:])
+
+	// never overwrite implicitly
+	if _, err := t.kv.get(key[:]); err != nil && err != ErrNotFound {
+		return err
+	} else if err == nil {
+		return errors.New("frontier already exists")
+	}
+
+	return t.kv.set(key[:], frontier.Address[:], 0)
+}
+
+func (t *kvStoreTxn) GetFrontier(hash block.Hash) (*block.Frontier, error) {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixFrontier
+	copy(key[1:], hash[:])
+
+	item, err := t.kv.get(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	frontier := block.Frontier{Hash: hash}
+	copy(frontier.Address[:], item.value)
+	return &frontier, nil
+}
+
+func (t *kvStoreTxn) GetFrontiers() ([]*block.Frontier, error) {
+	var frontiers []*block.Frontier
+
+	prefix := [...]byte{idPrefixFrontier}
+	err := t.kv.iterate(prefix[:], true, func(item *kvItem) error {
+		var frontier block.Frontier
+		copy(frontier.Address[:], item.value)
+		copy(frontier.Hash[:], item.key[1:])
+
+		frontiers = append(frontiers, &frontier)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return frontiers, nil
+}
+
+func (t *kvStoreTxn) DeleteFrontier(hash block.Hash) error {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixFrontier
+	copy(key[1:], hash[:])
+	return t.kv.delete(key[:])
+}
+
+func (t *kvStoreTxn) CountFrontiers() (uint64, error) {
+	prefix := [...]byte{idPrefixFrontier}
+	return t.count(prefix[:])
+}
+
+// AddConfirmation marks the block with the given hash as confirmed.
+func (t *kvStoreTxn) AddConfirmation(hash block.Hash) error {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixConfirmation
+	copy(key[1:], hash[:])
+
+	return t.kv.set(key[:], nil, 0)
+}
+
+// HasConfirmation reports whether the block with the given hash was confirmed.
+func (t *kvStoreTxn) HasConfirmation(hash block.Hash) (bool, error) {
+	var key [1 + block.HashSize]byte
+	key[0] = idPrefixConfirmation
+	copy(key[1:], hash[:])
+	return t.has(key[:])
+}
+
+// AddFork adds the given block to the list of competing blocks for the given
+// root.
+func (t *kvStoreTxn) AddFork(root block.Hash, blk block.Block) error {
+	hash := blk.Hash()
+	blockBytes, err := blk.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var key [1 + block.HashSize*2]byte
+	key[0] = idPrefixFork
+	copy(key[1:], root[:])
+	copy(key[1+block.HashSize:], hash[:])
+
+	// never overwrite implicitly
+	if _, err := t.kv.get(key[:]); err != nil && err != ErrNotFound {
+		return err
+	} else if err == nil {
+		return ErrBlockExists
+	}
+
+	return t.kv.set(key[:], blockBytes, blk.ID())
+}
+
+// GetForks retrieves the list of competing blocks for the given root.
+func (t *kvStoreTxn) GetForks(root block.Hash) ([]block.Block, error) {
+	var blocks []block.Block
+
+	var prefix [1 + block.HashSize]byte
+	prefix[0] = idPrefixFork
+	copy(prefix[1:], root[:])
+
+	err := t.kv.iterate(prefix[:], true, func(item *kvItem) error {
+		blk, err := decodeBlock(item)
+		if err != nil {
+			return err
+		}
+
+		blocks = append(blocks, blk)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// DeleteForks deletes the list of competing blocks for the given root.
+func (t *kvStoreTxn) DeleteForks(root block.Hash) error {
+	blocks, err := t.GetForks(root)
+	if err != nil {
+		return err
+	}
+
+	for _, blk := range blocks {
+		hash := blk.Hash()
+
+		var key [1 + block.HashSize*2]byte
+		key[0] = idPrefixFork
+		copy(key[1:], root[:])
+		copy(key[1+block.HashSize:], hash[:])
+
+		if err := t.kv.delete(key[:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *kvStoreTxn) AddPending(destination nano.Address, hash block.Hash, pending *Pending) error {
+	pendingBytes, err := pending.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var key [1 + PendingKeySize]byte
+	key[0] = idPrefixPending
+	copy(key[1:], destination[:])
+	copy(key[1+nano.AddressSize:], hash[:])
+
+	// never overwrite implicitly
+	if _, err := t.kv.get(key[:]); err != nil && err != ErrNotFound {
+		return err
+	} else if err == nil {
+		return errors.New("pending transaction already exists")
+	}
+
+	return t.kv.set(key[:], pendingBytes, 0)
+}
+
+func (t *kvStoreTxn) GetPending(destination nano.Address, hash block.Hash) (*Pending, error) {
+	var key [1 + PendingKeySize]byte
+	key[0] = idPrefixPending
+	copy(key[1:], destination[:])
+	copy(key[1+nano.AddressSize:], hash[:])
+
+	item, err := t.kv.get(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var pending Pending
+	if err := pending.UnmarshalBinary(item.value); err != nil {
+		return nil, err
+	}
+
+	return &pending, nil
+}
+
+func (t *kvStoreTxn) DeletePending(destination nano.Address, hash block.Hash) error {
+	var key [1 + PendingKeySize]byte
+	key[0] = idPrefixPending
+	copy(key[1:], destination[:])
+	copy(key[1+nano.AddressSize:], hash[:])
+	return t.kv.delete(key[:])
+}
+
+// WalkPending calls visit for every pending transaction of the given
+// destination address, ordered by the hash of the send block.
+func (t *kvStoreTxn) WalkPending(destination nano.Address, visit PendingWalkFunc) error {
+	var prefix [1 + nano.AddressSize]byte
+	prefix[0] = idPrefixPending
+	copy(prefix[1:], destination[:])
+
+	return t.kv.iterate(prefix[:], true, func(item *kvItem) error {
+		var pending Pending
+		if err := pending.UnmarshalBinary(item.value); err != nil {
+			return err
+		}
+
+		var hash block.Hash
+		copy(hash[:], item.key[1+nano.AddressSize:])
+
+		return visit(hash, &pending)
+	})
+}
+
+// WalkAllPending calls visit for every pending transaction in the database,
+// ordered by destination address and the hash of the send block.
+func (t *kvStoreTxn) WalkAllPending(visit AllPendingWalkFunc) error {
+	prefix := [...]byte{idPrefixPending}
+	return t.kv.iterate(prefix[:], true, func(item *kvItem) error {
+		var pending Pending
+		if err := pending.UnmarshalBinary(item.value); err != nil {
+			return err
+		}
+
+		var destination nano.Address
+		copy(destination[:], item.key[1:])
+
+		var hash block.Hash
+		copy(hash[:], item.key[1+nano.AddressSize:])
+
+		return visit(destination, hash, &pending)
+	})
+}
+
+// GetVoteSequence returns the sequence number of the last vote that was
+// created by the given representative. If no vote was created yet, 0 is
+// returned.
+func (t *kvStoreTxn) GetVoteSequence(address nano.Address) (uint64, error) {
+	var key [1 + nano.AddressSize]byte
+	key[0] = idPrefixVoteSequence
+	copy(key[1:], address[:])
+
+	item, err := t.kv.get(key[:])
+	if err != nil {
+		if err == ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(item.value), nil
+}
+
+// SetVoteSequence sets the sequence number of the last vote that was created
+// by the given representative.
+func (t *kvStoreTxn) SetVoteSequence(address nano.Address, sequence uint64) error {
+	var key [1 + nano.AddressSize]byte
+	key[0] = idPrefixVoteSequence
+	copy(key[1:], address[:])
+
+	var sequenceBytes [8]byte
+	binary.LittleEndian.PutUint64(sequenceBytes[:], sequence)
+	return t.kv.set(key[:], sequenceBytes[:], 0)
+}
+
+func (t *kvStoreTxn) setRepresentation(address nano.Address, amount nano.Balance) error {
+	var key [1 + nano.AddressSize]byte
+	key[0] = idPrefixRepresentation
+	copy(key[1:], address[:])
+
+	return t.kv.set(key[:], amount.Bytes(binary.BigEndian), 0)
+}
+
+func (t *kvStoreTxn) AddRepresentation(address nano.Address, amount nano.Balance) error {
+	oldAmount, err := t.GetRepresentation(address)
+	if err != nil {
+		return err
+	}
+
+	return t.setRepresentation(address, oldAmount.Add(amount))
+}
+
+func (t *kvStoreTxn) SubRepresentation(address nano.Address, amount nano.Balance) error {
+	oldAmount, err := t.GetRepresentation(address)
+	if err != nil {
+		return err
+	}
+
+	return t.setRepresentation(address, oldAmount.Sub(amount))
+}
+
+func (t *kvStoreTxn) GetRepresentation(address nano.Address) (nano.Balance, error) {
+	var key [1 + nano.AddressSize]byte
+	key[0] = idPrefixRepresentation
+	copy(key[1:], address[:])
+
+	item, err := t.kv.get(key[:])
+	if err != nil {
+		if err == ErrNotFound {
+			return nano.ZeroBalance, nil
+		}
+		return nano.ZeroBalance, err
+	}
+
+	var amount nano.Balance
+	if err := amount.UnmarshalBinary(item.value); err != nil {
+		return nano.ZeroBalance, err
+	}
+
+	return amount, nil
+}
+
+// WalkRepresentations calls visit for every representative in the database,
+// ordered by address.
+func (t *kvStoreTxn) WalkRepresentations(visit RepresentationWalkFunc) error {
+	prefix := [...]byte{idPrefixRepresentation}
+	return t.kv.iterate(prefix[:], true, func(item *kvItem) error {
+		var amount nano.Balance
+		if err := amount.UnmarshalBinary(item.value); err != nil {
+			return err
+		}
+
+		var address nano.Address
+		copy(address[:], item.key[1:])
+
+		return visit(address, amount)
+	})
+}
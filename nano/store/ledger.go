@@ -2,7 +2,7 @@ package store
 
 import (
 	"errors"
-	"fmt"
+	"time"
 
 	"github.com/alexbakker/gonano/nano"
 	"github.com/alexbakker/gonano/nano/block"
@@ -14,9 +14,7 @@ var (
 	ErrBadGenesis      = errors.New("genesis block in store doesn't match the given block")
 	ErrMissingPrevious = errors.New("previous block does not exist")
 	ErrMissingSource   = errors.New("source block does not exist")
-	ErrUnchecked       = errors.New("block was added to the unchecked list")
 	ErrFork            = errors.New("a fork was detected")
-	ErrNotFound        = errors.New("item not found in the store")
 )
 
 type Ledger struct {
@@ -36,6 +34,11 @@ func NewLedger(store Store, opts LedgerOptions) (*Ledger, error) {
 		return nil, err
 	}
 
+	// upgrade the database to the current schema version if needed
+	if err := ledger.migrate(); err != nil {
+		return nil, err
+	}
+
 	return &ledger, nil
 }
 
@@ -43,7 +46,7 @@ func (l *Ledger) setGenesis(blk *block.OpenBlock, balance nano.Balance) error {
 	hash := blk.Hash()
 
 	// make sure the work value is valid
-	if !blk.Valid(l.opts.Genesis.WorkThreshold) {
+	if !blk.Valid(l.opts.Genesis.WorkThresholds.Base) {
 		return errors.New("bad work for genesis block")
 	}
 
@@ -59,6 +62,15 @@ func (l *Ledger) setGenesis(blk *block.OpenBlock, balance nano.Balance) error {
 		}
 
 		if !empty {
+			// refuse to touch databases we don't understand
+			version, err := txn.GetVersion()
+			if err != nil {
+				return err
+			}
+			if version > SchemaVersion {
+				return ErrNewerSchema
+			}
+
 			// if the database is not empty, check if it has the same genesis
 			// block as the one in the given options
 			found, err := txn.HasBlock(hash)
@@ -69,20 +81,39 @@ func (l *Ledger) setGenesis(blk *block.OpenBlock, balance nano.Balance) error {
 				return ErrBadGenesis
 			}
 		} else {
+			if err := txn.SetVersion(SchemaVersion); err != nil {
+				return err
+			}
+
 			if err := txn.AddBlock(blk); err != nil {
 				return err
 			}
 
 			info := AddressInfo{
-				HeadBlock: hash,
-				RepBlock:  hash,
-				OpenBlock: hash,
-				Balance:   balance,
+				HeadBlock:  hash,
+				RepBlock:   hash,
+				OpenBlock:  hash,
+				Balance:    balance,
+				BlockCount: 1,
 			}
 			if err := txn.AddAddress(blk.Address, &info); err != nil {
 				return err
 			}
 
+			sideband := Sideband{
+				Height:    1,
+				Account:   blk.Address,
+				Balance:   balance,
+				Timestamp: time.Now().Unix(),
+			}
+			if err := txn.AddSideband(hash, &sideband); err != nil {
+				return err
+			}
+
+			if err := txn.AddRepresentation(blk.Representative, balance); err != nil {
+				return err
+			}
+
 			return txn.AddFrontier(&block.Frontier{
 				Address: blk.Address,
 				Hash:    hash,
@@ -98,7 +129,12 @@ func (l *Ledger) addOpenBlock(txn StoreTxn, blk *block.OpenBlock) error {
 
 	// make sure the signature of this block is valid
 	if !blk.Address.Verify(hash[:], blk.Signature[:]) {
-		return errors.New("bad block signature")
+		return ErrBadSignature
+	}
+
+	// the burn account can't be opened
+	if blk.Address == (nano.Address{}) {
+		return ErrOpenedBurnAccount
 	}
 
 	// make sure this address doesn't already exist
@@ -108,9 +144,14 @@ func (l *Ledger) addOpenBlock(txn StoreTxn, blk *block.OpenBlock) error {
 	}
 
 	// obtain the pending transaction info
-	pending, err := txn.GetPending(blk.Address, blk.SourceHash)
+	pending, err := l.getPending(txn, blk.Address, blk.SourceHash)
 	if err != nil {
-		return ErrMissingSource
+		return err
+	}
+
+	// funds sent by upgraded accounts can only be received with state blocks
+	if pending.Epoch != block.Epoch0 {
+		return ErrUnreceivable
 	}
 
 	// add address info
@@ -147,18 +188,42 @@ func (l *Ledger) addOpenBlock(txn StoreTxn, blk *block.OpenBlock) error {
 	return txn.AddBlock(blk)
 }
 
+// legacyFrontier returns the frontier of the account that owns the given
+// previous block of a legacy block and verifies the signature of the legacy
+// block against that account. If the previous block is not a frontier, the
+// block is a fork, but it's only reported as such if the signature is valid.
+func (l *Ledger) legacyFrontier(txn StoreTxn, previous block.Hash, hash block.Hash, signature block.Signature) (*block.Frontier, error) {
+	frontier, err := txn.GetFrontier(previous)
+	if err != nil {
+		if err != ErrNotFound {
+			return nil, err
+		}
+
+		address, err := l.blockAccount(txn, previous)
+		if err != nil {
+			return nil, err
+		}
+		if !address.Verify(hash[:], signature[:]) {
+			return nil, ErrBadSignature
+		}
+		return nil, ErrFork
+	}
+
+	if !frontier.Address.Verify(hash[:], signature[:]) {
+		return nil, ErrBadSignature
+	}
+
+	return frontier, nil
+}
+
 func (l *Ledger) addSendBlock(txn StoreTxn, blk *block.SendBlock) error {
 	hash := blk.Hash()
 
-	// make sure the hash of the previous block is a frontier
-	frontier, err := txn.GetFrontier(blk.Root())
+	// make sure the hash of the previous block is a frontier and the block was
+	// signed by the owner of the account
+	frontier, err := l.legacyFrontier(txn, blk.Root(), hash, blk.Signature)
 	if err != nil {
-		return ErrFork
-	}
-
-	// make sure the signature of this block is valid
-	if !frontier.Address.Verify(hash[:], blk.Signature[:]) {
-		return errors.New("bad block signature")
+		return err
 	}
 
 	// obtain account information and do some sanity checks
@@ -170,16 +235,22 @@ func (l *Ledger) addSendBlock(txn StoreTxn, blk *block.SendBlock) error {
 		return errors.New("unexpected head block for account")
 	}
 
+	// legacy blocks can't be added to accounts that were upgraded
+	if info.Epoch != block.Epoch0 {
+		return ErrEpochPosition
+	}
+
 	// make sure this is not a negative spend
 	// (apparently zero spends are allowed?)
 	if blk.Balance.Compare(info.Balance) == nano.BalanceCompBigger {
-		return fmt.Errorf("negative spend: %s > %s", blk.Balance, info.Balance)
+		return ErrNegativeSpend
 	}
 
 	// add this to the pending transaction list
 	pending := Pending{
 		Address: frontier.Address,
 		Amount:  info.Balance.Sub(blk.Balance),
+		Epoch:   info.Epoch,
 	}
 	if err := txn.AddPending(blk.Destination, hash, &pending); err != nil {
 		return err
@@ -197,7 +268,7 @@ func (l *Ledger) addSendBlock(txn StoreTxn, blk *block.SendBlock) error {
 	if err != nil {
 		return err
 	}
-	if err := txn.SubRepresentation(rep, blk.Balance); err != nil {
+	if err := txn.SubRepresentation(rep, pending.Amount); err != nil {
 		return err
 	}
 
@@ -220,15 +291,11 @@ func (l *Ledger) addSendBlock(txn StoreTxn, blk *block.SendBlock) error {
 func (l *Ledger) addReceiveBlock(txn StoreTxn, blk *block.ReceiveBlock) error {
 	hash := blk.Hash()
 
-	// make sure the hash of the previous block is a frontier
-	frontier, err := txn.GetFrontier(blk.Root())
+	// make sure the hash of the previous block is a frontier and the block was
+	// signed by the owner of the account
+	frontier, err := l.legacyFrontier(txn, blk.Root(), hash, blk.Signature)
 	if err != nil {
-		return ErrFork
-	}
-
-	// make sure the signature of this block is valid
-	if !frontier.Address.Verify(hash[:], blk.Signature[:]) {
-		return errors.New("bad block signature")
+		return err
 	}
 
 	// obtain account information and do some sanity checks
@@ -240,10 +307,20 @@ func (l *Ledger) addReceiveBlock(txn StoreTxn, blk *block.ReceiveBlock) error {
 		return errors.New("unexpected head block for account")
 	}
 
+	// legacy blocks can't be added to accounts that were upgraded
+	if info.Epoch != block.Epoch0 {
+		return ErrEpochPosition
+	}
+
 	// obtain the pending transaction info
-	pending, err := txn.GetPending(frontier.Address, blk.SourceHash)
+	pending, err := l.getPending(txn, frontier.Address, blk.SourceHash)
 	if err != nil {
-		return ErrMissingSource
+		return err
+	}
+
+	// funds sent by upgraded accounts can only be received with state blocks
+	if pending.Epoch != block.Epoch0 {
+		return ErrUnreceivable
 	}
 
 	// update the address info
@@ -286,15 +363,11 @@ func (l *Ledger) addReceiveBlock(txn StoreTxn, blk *block.ReceiveBlock) error {
 func (l *Ledger) addChangeBlock(txn StoreTxn, blk *block.ChangeBlock) error {
 	hash := blk.Hash()
 
-	// make sure the hash of the previous block is a frontier
-	frontier, err := txn.GetFrontier(blk.Root())
+	// make sure the hash of the previous block is a frontier and the block was
+	// signed by the owner of the account
+	frontier, err := l.legacyFrontier(txn, blk.Root(), hash, blk.Signature)
 	if err != nil {
-		return ErrFork
-	}
-
-	// make sure the signature of this block is valid
-	if !frontier.Address.Verify(hash[:], blk.Signature[:]) {
-		return errors.New("bad block signature")
+		return err
 	}
 
 	// obtain account information and do some sanity checks
@@ -306,6 +379,11 @@ func (l *Ledger) addChangeBlock(txn StoreTxn, blk *block.ChangeBlock) error {
 		return errors.New("unexpected head block for account")
 	}
 
+	// legacy blocks can't be added to accounts that were upgraded
+	if info.Epoch != block.Epoch0 {
+		return ErrEpochPosition
+	}
+
 	// obtain the old representative
 	oldRep, err := l.getRepresentative(txn, frontier.Address)
 	if err != nil {
@@ -346,62 +424,95 @@ func (l *Ledger) addChangeBlock(txn StoreTxn, blk *block.ChangeBlock) error {
 func (l *Ledger) addStateBlock(txn StoreTxn, blk *block.StateBlock) error {
 	hash := blk.Hash()
 
+	// epoch blocks are signed by the epoch signer instead of the owner of the
+	// account
+	epoch, isEpoch, err := l.epochOf(txn, blk)
+	if err != nil {
+		return err
+	}
+	if isEpoch {
+		return l.addEpochBlock(txn, blk, epoch)
+	}
+
 	// make sure the signature of this block is valid
 	if !blk.Address.Verify(hash[:], blk.Signature[:]) {
-		return errors.New("bad block signature")
+		return ErrBadSignature
 	}
 
 	// obtain account information if possible
 	info, err := txn.GetAddress(blk.Address)
 	if err != nil {
-		// todo: check for key not found error
-		if blk.IsOpen() {
-			// account doesn't exist
-			// obtain the pending transaction info
-			pending, err := txn.GetPending(blk.Address, blk.Link)
-			if err != nil {
-				return ErrMissingSource
-			}
+		if err != ErrNotFound {
+			return err
+		}
 
-			// add address info
-			info := AddressInfo{
-				HeadBlock: hash,
-				RepBlock:  hash,
-				OpenBlock: hash,
-				Balance:   pending.Amount,
-			}
-			if err := txn.AddAddress(blk.Address, &info); err != nil {
-				return err
-			}
+		// the previous block belongs to another account
+		if !blk.IsOpen() {
+			return ErrBlockPosition
+		}
 
-			// delete the pending transaction
-			if err := txn.DeletePending(blk.Address, blk.Link); err != nil {
-				return err
-			}
+		// the burn account can't be opened
+		if blk.Address == (nano.Address{}) {
+			return ErrOpenedBurnAccount
+		}
 
-			// update representative voting weight
-			if err := txn.AddRepresentation(blk.Representative, pending.Amount); err != nil {
-				return err
-			}
+		// account doesn't exist
+		// obtain the pending transaction info
+		pending, err := l.getPending(txn, blk.Address, blk.Link)
+		if err != nil {
+			return err
+		}
+		if !blk.Balance.Equal(pending.Amount) {
+			return ErrBalanceMismatch
+		}
 
-			// add a frontier for this address
-			frontier := block.Frontier{
-				Address: blk.Address,
-				Hash:    hash,
-			}
-			if err := txn.AddFrontier(&frontier); err != nil {
-				return err
-			}
+		// add address info, the account starts out in the epoch of the sender
+		info := AddressInfo{
+			HeadBlock: hash,
+			RepBlock:  hash,
+			OpenBlock: hash,
+			Balance:   pending.Amount,
+			Epoch:     pending.Epoch,
+		}
+		if err := txn.AddAddress(blk.Address, &info); err != nil {
+			return err
+		}
 
-			// finally, add the block
-			return txn.AddBlock(blk)
+		// delete the pending transaction
+		if err := txn.DeletePending(blk.Address, blk.Link); err != nil {
+			return err
 		}
-		return err
+
+		// update representative voting weight
+		if err := txn.AddRepresentation(blk.Representative, pending.Amount); err != nil {
+			return err
+		}
+
+		// add a frontier for this address
+		frontier := block.Frontier{
+			Address: blk.Address,
+			Hash:    hash,
+		}
+		if err := txn.AddFrontier(&frontier); err != nil {
+			return err
+		}
+
+		// finally, add the block
+		return txn.AddBlock(blk)
 	}
 
-	// make sure the hash of the previous block is a frontier
-	frontier, err := txn.GetFrontier(blk.PreviousHash)
-	if err != nil {
+	// make sure the previous block is the head of this account
+	if blk.IsOpen() {
+		return ErrFork
+	}
+	if info.HeadBlock != blk.PreviousHash {
+		address, err := l.blockAccount(txn, blk.PreviousHash)
+		if err != nil {
+			return err
+		}
+		if address != blk.Address {
+			return ErrBlockPosition
+		}
 		return ErrFork
 	}
 
@@ -411,74 +522,68 @@ func (l *Ledger) addStateBlock(txn StoreTxn, blk *block.StateBlock) error {
 		return err
 	}
 
-	if blk.Link.IsZero() {
-		if !blk.Balance.Equal(info.Balance) {
-			fmt.Printf("%s - %s\n", blk.Balance, info.Balance)
-			return errors.New("balance change not allowed in change block")
+	switch blk.Balance.Compare(info.Balance) {
+	case nano.BalanceCompBigger:
+		// receive
+		// obtain the pending transaction info
+		pending, err := l.getPending(txn, blk.Address, blk.Link)
+		if err != nil {
+			return err
 		}
-		// update representative voting weight
-		if err := txn.SubRepresentation(rep, info.Balance); err != nil {
+		if !blk.Balance.Equal(info.Balance.Add(pending.Amount)) {
+			return ErrBalanceMismatch
+		}
+		// receiving funds from an upgraded account upgrades this account
+		if pending.Epoch > info.Epoch {
+			info.Epoch = pending.Epoch
+		}
+		// delete the pending transaction
+		if err := txn.DeletePending(blk.Address, blk.Link); err != nil {
 			return err
 		}
-		if err := txn.AddRepresentation(blk.Representative, info.Balance); err != nil {
+	case nano.BalanceCompSmaller:
+		// send
+		pending := Pending{
+			Address: blk.Address,
+			Amount:  info.Balance.Sub(blk.Balance),
+			Epoch:   info.Epoch,
+		}
+		// add this to the pending transaction list
+		if err := txn.AddPending(nano.Address(blk.Link), hash, &pending); err != nil {
 			return err
 		}
-		info.RepBlock = hash
-	} else if !blk.IsOpen() {
-		switch blk.Balance.Compare(info.Balance) {
-		case nano.BalanceCompBigger:
-			// receive
-			// obtain the pending transaction info
-			pending, err := txn.GetPending(blk.Address, blk.Link)
-			if err != nil {
-				return ErrMissingSource
-			}
-			// update representative voting weight
-			if err := txn.AddRepresentation(rep, pending.Amount); err != nil {
-				return err
-			}
-			// delete the pending transaction
-			if err := txn.DeletePending(blk.Address, blk.Link); err != nil {
-				return err
-			}
-			info.Balance = info.Balance.Add(pending.Amount)
-		case nano.BalanceCompSmaller:
-			// send
-			pending := Pending{
-				Address: frontier.Address,
-				Amount:  info.Balance.Sub(blk.Balance),
-			}
-			// update representative voting weight
-			if err := txn.SubRepresentation(rep, pending.Amount); err != nil {
-				return err
-			}
-			// add this to the pending transaction list
-			if err := txn.AddPending(nano.Address(blk.Link), hash, &pending); err != nil {
-				return err
-			}
-			info.Balance = info.Balance.Sub(pending.Amount)
-		case nano.BalanceCompEqual:
-			return errors.New("zero spend not allowed")
+	case nano.BalanceCompEqual:
+		// zero spends are not allowed
+		if !blk.Link.IsZero() {
+			return ErrBalanceMismatch
 		}
 	}
 
+	// update representative voting weight
+	if err := txn.SubRepresentation(rep, info.Balance); err != nil {
+		return err
+	}
+	if err := txn.AddRepresentation(blk.Representative, blk.Balance); err != nil {
+		return err
+	}
+
 	// update the address info
 	info.HeadBlock = hash
+	info.RepBlock = hash
+	info.Balance = blk.Balance
 	if err := txn.UpdateAddress(blk.Address, info); err != nil {
 		return err
 	}
 
 	// update the frontier of this account
-	if !blk.IsOpen() {
-		if err := txn.DeleteFrontier(frontier.Hash); err != nil {
-			return err
-		}
+	if err := txn.DeleteFrontier(blk.PreviousHash); err != nil {
+		return err
 	}
-	frontier = &block.Frontier{
+	frontier := block.Frontier{
 		Address: blk.Address,
 		Hash:    hash,
 	}
-	if err := txn.AddFrontier(frontier); err != nil {
+	if err := txn.AddFrontier(&frontier); err != nil {
 		return err
 	}
 
@@ -489,8 +594,9 @@ func (l *Ledger) addStateBlock(txn StoreTxn, blk *block.StateBlock) error {
 func (l *Ledger) addBlock(txn StoreTxn, blk block.Block) error {
 	hash := blk.Hash()
 
-	// make sure the work value is valid
-	if !blk.Valid(l.opts.Genesis.WorkThreshold) {
+	// make sure the work value is valid, the exact threshold is checked once
+	// the subtype and epoch of the block are known
+	if !blk.Valid(l.opts.Genesis.WorkThresholds.Min()) {
 		return ErrBadWork
 	}
 
@@ -519,15 +625,26 @@ func (l *Ledger) addBlock(txn StoreTxn, blk block.Block) error {
 		case *block.ChangeBlock:
 			return ErrMissingPrevious
 		case *block.StateBlock:
-			if b.IsOpen() {
+			if !b.IsOpen() {
+				return ErrMissingPrevious
+			}
+			// the link of epoch open blocks is not a block
+			if _, ok := l.opts.Genesis.EpochOf(b.Link); !ok {
 				return ErrMissingSource
 			}
-			return ErrMissingPrevious
 		default:
 			return block.ErrBadBlockType
 		}
 	}
 
+	threshold, err := l.workThreshold(txn, blk)
+	if err != nil {
+		return err
+	}
+	if !blk.Valid(threshold) {
+		return ErrBadWork
+	}
+
 	switch b := blk.(type) {
 	case *block.OpenBlock:
 		err = l.addOpenBlock(txn, b)
@@ -547,10 +664,126 @@ func (l *Ledger) addBlock(txn StoreTxn, blk block.Block) error {
 		return err
 	}
 
+	if err := l.addSideband(txn, blk); err != nil {
+		return err
+	}
+
 	// flush if needed
 	return txn.Flush()
 }
 
+// addSideband stores the sideband of the given block, which was just added to
+// the head of its account chain, and links the previous block to it.
+func (l *Ledger) addSideband(txn StoreTxn, blk block.Block) error {
+	hash := blk.Hash()
+
+	// the account of open blocks is their root
+	address := nano.Address(ForkRoot(blk))
+	if prevHash := blk.Previous(); !prevHash.IsZero() {
+		prev, err := txn.GetSideband(prevHash)
+		if err != nil {
+			return err
+		}
+
+		address = prev.Account
+		prev.Successor = hash
+		if err := txn.UpdateSideband(prevHash, prev); err != nil {
+			return err
+		}
+	}
+
+	info, err := txn.GetAddress(address)
+	if err != nil {
+		return err
+	}
+
+	info.BlockCount++
+	if err := txn.UpdateAddress(address, info); err != nil {
+		return err
+	}
+
+	return txn.AddSideband(hash, &Sideband{
+		Height:    info.BlockCount,
+		Account:   address,
+		Balance:   info.Balance,
+		Timestamp: time.Now().Unix(),
+		Epoch:     info.Epoch,
+	})
+}
+
+// workThreshold returns the work threshold for the given block, which depends
+// on its subtype and on the epoch of the account after the block. The
+// previous block must be in the ledger.
+func (l *Ledger) workThreshold(txn StoreTxn, blk block.Block) (uint64, error) {
+	epoch, subtype, err := l.blockDetails(txn, blk)
+	if err != nil {
+		return 0, err
+	}
+
+	return l.opts.Genesis.WorkThresholds.Threshold(epoch, subtype), nil
+}
+
+// blockDetails returns the subtype of the given block and the epoch of the
+// account after the block, as if it were added to the ledger. The previous
+// block must be in the ledger.
+func (l *Ledger) blockDetails(txn StoreTxn, blk block.Block) (block.Epoch, block.Subtype, error) {
+	// legacy blocks can only be added to accounts in the first epoch
+	switch blk.(type) {
+	case *block.SendBlock:
+		return block.Epoch0, block.SubtypeSend, nil
+	case *block.ReceiveBlock:
+		return block.Epoch0, block.SubtypeReceive, nil
+	case *block.OpenBlock:
+		return block.Epoch0, block.SubtypeOpen, nil
+	case *block.ChangeBlock:
+		return block.Epoch0, block.SubtypeChange, nil
+	}
+
+	b, ok := blk.(*block.StateBlock)
+	if !ok {
+		return block.Epoch0, block.SubtypeInvalid, block.ErrBadBlockType
+	}
+
+	epoch, isEpoch, err := l.epochOf(txn, b)
+	if err != nil {
+		return block.Epoch0, block.SubtypeInvalid, err
+	}
+	if isEpoch {
+		return epoch, block.SubtypeEpoch, nil
+	}
+
+	prevBalance := nano.ZeroBalance
+	if !b.IsOpen() {
+		prev, err := txn.GetSideband(b.PreviousHash)
+		if err != nil {
+			return block.Epoch0, block.SubtypeInvalid, err
+		}
+		prevBalance = prev.Balance
+		epoch = prev.Epoch
+	}
+
+	switch b.Balance.Compare(prevBalance) {
+	case nano.BalanceCompSmaller:
+		return epoch, block.SubtypeSend, nil
+	case nano.BalanceCompBigger:
+		// receiving funds from an upgraded account upgrades the account
+		pending, err := txn.GetPending(b.Address, b.Link)
+		if err != nil && err != ErrNotFound {
+			return block.Epoch0, block.SubtypeInvalid, err
+		}
+		if err == nil && pending.Epoch > epoch {
+			epoch = pending.Epoch
+		}
+
+		if b.IsOpen() {
+			return epoch, block.SubtypeOpen, nil
+		}
+		return epoch, block.SubtypeReceive, nil
+	default:
+		return epoch, block.SubtypeChange, nil
+	}
+}
+
 func (l *Ledger) addUncheckedBlock(txn StoreTxn, parentHash block.Hash, blk block.Block, kind UncheckedKind) error {
 	found, err := txn.HasUncheckedBlock(parentHash, kind)
 	if err != nil {
@@ -578,28 +811,33 @@ func (l *Ledger) processUncheckedBlock(txn StoreTxn, blk block.Block, kind Unche
 			return err
 		}
 
-		if err := l.processBlock(txn, uncheckedBlk); err == nil {
-			// delete from the unchecked list if successful
-			if err := txn.DeleteUncheckedBlock(hash, kind); err != nil {
-				return err
-			}
+		// if the block still has a gap, it's added to the unchecked list again
+		// under the hash of the missing block
+		if _, err := l.processBlock(txn, uncheckedBlk); err != nil {
+			return err
+		}
+
+		if err := txn.DeleteUncheckedBlock(hash, kind); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (l *Ledger) processBlock(txn StoreTxn, blk block.Block) error {
-	err := l.addBlock(txn, blk)
+func (l *Ledger) processBlock(txn StoreTxn, blk block.Block) (ProcessResult, error) {
+	res, err := processResult(l.addBlock(txn, blk))
+	if err != nil {
+		return res, err
+	}
 
-	switch err {
-	case ErrMissingPrevious:
+	switch res {
+	case ProcessGapPrevious:
 		// add to unchecked list
 		if err := l.addUncheckedBlock(txn, blk.Root(), blk, UncheckedKindPrevious); err != nil {
-			return err
+			return res, err
 		}
-		return ErrUnchecked
-	case ErrMissingSource:
+	case ProcessGapSource:
 		var source block.Hash
 		switch b := blk.(type) {
 		case *block.ReceiveBlock:
@@ -609,59 +847,174 @@ func (l *Ledger) processBlock(txn StoreTxn, blk block.Block) error {
 		case *block.StateBlock:
 			source = b.Link
 		default:
-			return errors.New("unexpected block type")
+			return res, errors.New("unexpected block type")
 		}
 
 		// add to unchecked list
 		if err := l.addUncheckedBlock(txn, source, blk, UncheckedKindSource); err != nil {
-			return err
+			return res, err
 		}
-
-		return ErrUnchecked
-	case nil:
-		fmt.Printf("added block: %s\n", blk.Hash())
-
+	case ProcessProgress:
 		// try to process any unchecked child blocks
 		if err := l.processUncheckedBlock(txn, blk, UncheckedKindPrevious); err != nil {
-			return err
+			return res, err
 		}
 
 		if err := l.processUncheckedBlock(txn, blk, UncheckedKindSource); err != nil {
-			return err
+			return res, err
+		}
+	case ProcessFork:
+		// keep track of the competing block so that the fork can be resolved
+		if err := l.addFork(txn, blk); err != nil {
+			return res, err
 		}
+	}
 
-		return nil
-	case ErrBlockExists:
-		// ignore
-		return nil
-	default:
-		fmt.Printf("error adding block %s: %s\n", blk.Hash(), err)
-		return err
+	return res, nil
+}
+
+// getPending returns the pending transaction for the given source block. If
+// it doesn't exist, ErrMissingSource is returned if the source block is
+// unknown and ErrUnreceivable otherwise.
+func (l *Ledger) getPending(txn StoreTxn, address nano.Address, source block.Hash) (*Pending, error) {
+	pending, err := txn.GetPending(address, source)
+	if err != ErrNotFound {
+		return pending, err
 	}
+
+	found, err := txn.HasBlock(source)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrMissingSource
+	}
+
+	return nil, ErrUnreceivable
 }
 
-func (l *Ledger) AddBlock(blk block.Block) error {
-	return l.db.Update(func(txn StoreTxn) error {
-		err := l.processBlock(txn, blk)
-		if err != nil && err != ErrUnchecked {
-			fmt.Printf("try add err: %s\n", err)
-		}
+// AddBlock adds the given block to the ledger and returns the result. If the
+// block competes with a block that is already in the ledger, it is recorded as
+// a fork and ProcessFork is returned. See ResolveFork. An error is only
+// returned if the store failed.
+func (l *Ledger) AddBlock(blk block.Block) (ProcessResult, error) {
+	var res ProcessResult
 
-		return nil
+	err := l.db.Update(func(txn StoreTxn) (err error) {
+		res, err = l.processBlock(txn, blk)
+		return err
 	})
+
+	return res, err
 }
 
-func (l *Ledger) AddBlocks(blocks []block.Block) error {
-	return l.db.Update(func(txn StoreTxn) error {
-		for _, blk := range blocks {
-			err := l.processBlock(txn, blk)
-			if err != nil && err != ErrUnchecked {
-				fmt.Printf("try add err: %s\n", err)
+// AddBlocks adds the given blocks to the ledger in order and returns the
+// result for every block. See AddBlock.
+func (l *Ledger) AddBlocks(blocks []block.Block) ([]ProcessResult, error) {
+	results := make([]ProcessResult, len(blocks))
+
+	err := l.db.Update(func(txn StoreTxn) error {
+		for i, blk := range blocks {
+			res, err := l.processBlock(txn, blk)
+			if err != nil {
+				return err
 			}
+			results[i] = res
 		}
 
 		return nil
 	})
+
+	return results, err
+}
+
+// VerifyBlock checks the work and the signature of the given block without
+// adding it to the ledger. The signer of a legacy block that isn't an open
+// block can only be determined if its previous block is in the ledger, so
+// ErrMissingPrevious is returned if it isn't.
+func (l *Ledger) VerifyBlock(blk block.Block) error {
+	return l.db.View(func(txn StoreTxn) error {
+		return l.verifyBlock(txn, blk)
+	})
+}
+
+func (l *Ledger) verifyBlock(txn StoreTxn, blk block.Block) error {
+	if !blk.Valid(l.opts.Genesis.WorkThresholds.Min()) {
+		return ErrBadWork
+	}
+
+	// the exact work threshold can only be determined if the previous block
+	// is known
+	previous := blk.Previous()
+	found := true
+	if !previous.IsZero() {
+		var err error
+		if found, err = txn.HasBlock(previous); err != nil {
+			return err
+		}
+	}
+
+	if found {
+		threshold, err := l.workThreshold(txn, blk)
+		if err != nil {
+			return err
+		}
+		if !blk.Valid(threshold) {
+			return ErrBadWork
+		}
+	}
+
+	hash := blk.Hash()
+	var signature block.Signature
+	switch b := blk.(type) {
+	case *block.OpenBlock:
+		if !b.Address.Verify(hash[:], b.Signature[:]) {
+			return ErrBadSignature
+		}
+		return nil
+	case *block.StateBlock:
+		return l.verifyStateSignature(b)
+	case *block.SendBlock:
+		signature = b.Signature
+	case *block.ReceiveBlock:
+		signature = b.Signature
+	case *block.ChangeBlock:
+		signature = b.Signature
+	default:
+		return block.ErrBadBlockType
+	}
+
+	// legacy blocks are signed by the owner of the previous block
+	if !found {
+		return ErrMissingPrevious
+	}
+	address, err := l.blockAccount(txn, previous)
+	if err != nil {
+		return err
+	}
+	if !address.Verify(hash[:], signature[:]) {
+		return ErrBadSignature
+	}
+
+	return nil
+}
+
+// verifyStateSignature checks whether the given state block was signed by the
+// owner of its account or, if its link is an epoch link, by the signer of that
+// epoch.
+func (l *Ledger) verifyStateSignature(blk *block.StateBlock) error {
+	hash := blk.Hash()
+	if blk.Address.Verify(hash[:], blk.Signature[:]) {
+		return nil
+	}
+
+	if epoch, ok := l.opts.Genesis.EpochOf(blk.Link); ok {
+		if l.opts.Genesis.Epochs[epoch].Signer.Verify(hash[:], blk.Signature[:]) {
+			return nil
+		}
+	}
+
+	return ErrBadSignature
 }
 
 func (l *Ledger) CountBlocks() (uint64, error) {
@@ -723,7 +1076,7 @@ func (l *Ledger) GetFrontier(address nano.Address) (block.Hash, error) {
 
 		info, err := txn.GetAddress(address)
 		if err != nil {
-			return nil
+			return err
 		}
 
 		hash = info.HeadBlock
@@ -733,6 +1086,216 @@ func (l *Ledger) GetFrontier(address nano.Address) (block.Hash, error) {
 	return hash, err
 }
 
+func (l *Ledger) GetBlock(hash block.Hash) (block.Block, error) {
+	var blk block.Block
+
+	err := l.db.View(func(txn StoreTxn) error {
+		found, err := txn.HasBlock(hash)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrNotFound
+		}
+
+		blk, err = txn.GetBlock(hash)
+		return err
+	})
+
+	return blk, err
+}
+
+func (l *Ledger) GetAddressInfo(address nano.Address) (*AddressInfo, error) {
+	var info *AddressInfo
+
+	err := l.db.View(func(txn StoreTxn) error {
+		found, err := txn.HasAddress(address)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrNotFound
+		}
+
+		info, err = txn.GetAddress(address)
+		return err
+	})
+
+	return info, err
+}
+
+// GetRepresentation returns the voting weight of the given representative.
+func (l *Ledger) GetRepresentation(address nano.Address) (nano.Balance, error) {
+	var weight nano.Balance
+
+	err := l.db.View(func(txn StoreTxn) (err error) {
+		weight, err = txn.GetRepresentation(address)
+		return err
+	})
+
+	return weight, err
+}
+
+// Confirm marks the block with the given hash as confirmed by the network.
+// Confirmed blocks can no longer be rolled back.
+func (l *Ledger) Confirm(hash block.Hash) error {
+	return l.db.Update(func(txn StoreTxn) error {
+		found, err := txn.HasBlock(hash)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrNotFound
+		}
+
+		return txn.AddConfirmation(hash)
+	})
+}
+
+// Confirmed reports whether the block with the given hash was confirmed by the
+// network.
+func (l *Ledger) Confirmed(hash block.Hash) (bool, error) {
+	var confirmed bool
+
+	err := l.db.View(func(txn StoreTxn) (err error) {
+		confirmed, err = txn.HasConfirmation(hash)
+		return err
+	})
+
+	return confirmed, err
+}
+
+// NextVoteSequence increments the vote sequence number of the given
+// representative and returns it. The new value is persisted before it's
+// returned, so a sequence number is never handed out twice.
+func (l *Ledger) NextVoteSequence(address nano.Address) (uint64, error) {
+	var sequence uint64
+
+	err := l.db.Update(func(txn StoreTxn) error {
+		last, err := txn.GetVoteSequence(address)
+		if err != nil {
+			return err
+		}
+
+		sequence = last + 1
+		return txn.SetVoteSequence(address, sequence)
+	})
+
+	return sequence, err
+}
+
+// WalkFrontiers calls visit for the frontier of every address in the ledger,
+// starting at the given address. Frontiers are visited in order of address. If
+// since is not zero, only the frontiers that were added to the ledger at or
+// after that time are visited. Frontiers that were added before sidebands were
+// introduced are considered to be older than any time.
+func (l *Ledger) WalkFrontiers(start nano.Address, since time.Time, visit func(frontier *block.Frontier) error) error {
+	return l.db.View(func(txn StoreTxn) error {
+		return txn.SeekAddresses(start, func(address nano.Address, info *AddressInfo) error {
+			if !since.IsZero() {
+				sideband, err := txn.GetSideband(info.HeadBlock)
+				if err != nil {
+					return err
+				}
+				if sideband.Timestamp < since.Unix() {
+					return nil
+				}
+			}
+
+			return visit(&block.Frontier{
+				Address: address,
+				Hash:    info.HeadBlock,
+			})
+		})
+	})
+}
+
+// WalkBlocks calls visit for every block in the ledger, starting at the given
+// hash. Blocks are visited in order of hash.
+func (l *Ledger) WalkBlocks(start block.Hash, visit BlockWalkFunc) error {
+	return l.db.View(func(txn StoreTxn) error {
+		return txn.SeekBlocks(start, visit)
+	})
+}
+
+// WalkPending calls visit for every pending transaction of the given address.
+func (l *Ledger) WalkPending(address nano.Address, visit PendingWalkFunc) error {
+	return l.db.View(func(txn StoreTxn) error {
+		return txn.WalkPending(address, visit)
+	})
+}
+
+// WalkRepresentations calls visit for every representative in the ledger and
+// its voting weight.
+func (l *Ledger) WalkRepresentations(visit RepresentationWalkFunc) error {
+	return l.db.View(func(txn StoreTxn) error {
+		return txn.WalkRepresentations(visit)
+	})
+}
+
+// GetRepresentative returns the current representative of the given address.
+func (l *Ledger) GetRepresentative(address nano.Address) (nano.Address, error) {
+	var rep nano.Address
+
+	err := l.db.View(func(txn StoreTxn) (err error) {
+		rep, err = l.getRepresentative(txn, address)
+		return err
+	})
+
+	return rep, err
+}
+
+// GetBlockAccount returns the address of the account chain the block with the
+// given hash belongs to.
+func (l *Ledger) GetBlockAccount(hash block.Hash) (nano.Address, error) {
+	var address nano.Address
+
+	err := l.db.View(func(txn StoreTxn) (err error) {
+		address, err = l.blockAccount(txn, hash)
+		return err
+	})
+
+	return address, err
+}
+
+// GetBlockBalance returns the balance of the account after the block with the
+// given hash was added to its chain.
+func (l *Ledger) GetBlockBalance(hash block.Hash) (nano.Balance, error) {
+	var balance nano.Balance
+
+	err := l.db.View(func(txn StoreTxn) (err error) {
+		balance, err = l.blockBalance(txn, hash)
+		return err
+	})
+
+	return balance, err
+}
+
+// GetBlockAmount returns the amount that was sent or received by the block
+// with the given hash.
+func (l *Ledger) GetBlockAmount(hash block.Hash) (nano.Balance, error) {
+	var amount nano.Balance
+
+	err := l.db.View(func(txn StoreTxn) (err error) {
+		amount, err = l.blockAmount(txn, hash)
+		return err
+	})
+
+	return amount, err
+}
+
+// GetSideband returns the sideband of the block with the given hash.
+func (l *Ledger) GetSideband(hash block.Hash) (*Sideband, error) {
+	var sideband *Sideband
+
+	err := l.db.View(func(txn StoreTxn) (err error) {
+		sideband, err = txn.GetSideband(hash)
+		return err
+	})
+
+	return sideband, err
+}
+
 func (l *Ledger) getRepresentative(txn StoreTxn, address nano.Address) (nano.Address, error) {
 	info, err := txn.GetAddress(address)
 	if err != nil {
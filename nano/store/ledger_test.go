@@ -1,12 +1,16 @@
 package store
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"io/ioutil"
-	"os"
 	"testing"
 
+	"github.com/alexbakker/gonano/nano"
 	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/crypto/ed25519"
+	"github.com/alexbakker/gonano/nano/crypto/random"
 	"github.com/alexbakker/gonano/nano/node/proto"
 	"github.com/alexbakker/gonano/nano/store/genesis"
 )
@@ -14,25 +18,24 @@ import (
 type testLedger struct {
 	*Ledger
 	store Store
-	dir   string
 }
 
-func initTestLedger(t testing.TB) *testLedger {
-	dir, err := ioutil.TempDir("", "gonano_test_")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	store, err := NewBadgerStore(dir)
-	if err != nil {
-		t.Fatal(err)
-	}
+type testAccount struct {
+	address nano.Address
+	key     ed25519.PrivateKey
+}
 
+func initTestLedger(t testing.TB) *testLedger {
 	gen, err := genesis.Get(proto.NetworkLive)
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	return initTestLedgerGenesis(t, gen)
+}
+
+func initTestLedgerGenesis(t testing.TB, gen genesis.Genesis) *testLedger {
+	store := NewMemoryStore()
 	ledger, err := NewLedger(store, LedgerOptions{Genesis: gen})
 	if err != nil {
 		t.Fatal(err)
@@ -41,7 +44,6 @@ func initTestLedger(t testing.TB) *testLedger {
 	return &testLedger{
 		Ledger: ledger,
 		store:  store,
-		dir:    dir,
 	}
 }
 
@@ -49,9 +51,135 @@ func (l *testLedger) Close(t testing.TB) {
 	if err := l.store.Close(); err != nil {
 		t.Error(err)
 	}
-	if err := os.RemoveAll(l.dir); err != nil {
+}
+
+func newTestAccount(t testing.TB) *testAccount {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var address nano.Address
+	copy(address[:], key.Public().(ed25519.PublicKey))
+	return &testAccount{address: address, key: key}
+}
+
+// newTestGenesis creates a genesis block for a test network with a work
+// threshold of zero, along with the account that owns all of its funds.
+func newTestGenesis(t testing.TB) (genesis.Genesis, *testAccount) {
+	acc := newTestAccount(t)
+	blk := block.OpenBlock{
+		SourceHash:     block.Hash(acc.address),
+		Representative: acc.address,
+		Address:        acc.address,
+	}
+	acc.sign(&blk.Signature, blk.Hash())
+
+	gen := genesis.Genesis{
+		Block:   blk,
+		Balance: nano.ParseBalanceInts(0xffffffffffffffff, 0xffffffffffffffff),
+	}
+	return gen, acc
+}
+
+func (a *testAccount) sign(sig *block.Signature, hash block.Hash) {
+	copy(sig[:], ed25519.Sign(a.key, hash[:]))
+}
+
+func (a *testAccount) state(previous block.Hash, rep nano.Address, balance nano.Balance, link block.Hash) *block.StateBlock {
+	blk := block.StateBlock{
+		Address:        a.address,
+		PreviousHash:   previous,
+		Representative: rep,
+		Balance:        balance,
+		Link:           link,
+	}
+	a.sign(&blk.Signature, blk.Hash())
+	return &blk
+}
+
+func (a *testAccount) send(previous block.Hash, destination nano.Address, balance nano.Balance) *block.SendBlock {
+	blk := block.SendBlock{
+		PreviousHash: previous,
+		Destination:  destination,
+		Balance:      balance,
+	}
+	a.sign(&blk.Signature, blk.Hash())
+	return &blk
+}
+
+func (a *testAccount) open(source block.Hash, rep nano.Address) *block.OpenBlock {
+	blk := block.OpenBlock{
+		SourceHash:     source,
+		Representative: rep,
+		Address:        a.address,
+	}
+	a.sign(&blk.Signature, blk.Hash())
+	return &blk
+}
+
+func (a *testAccount) receive(previous block.Hash, source block.Hash) *block.ReceiveBlock {
+	blk := block.ReceiveBlock{
+		PreviousHash: previous,
+		SourceHash:   source,
+	}
+	a.sign(&blk.Signature, blk.Hash())
+	return &blk
+}
+
+func randomAddress(t testing.TB) nano.Address {
+	var address nano.Address
+	if err := random.Bytes(address[:]); err != nil {
+		t.Fatal(err)
+	}
+	return address
+}
+
+func randomHash(t testing.TB) block.Hash {
+	return block.Hash(randomAddress(t))
+}
+
+func mustAddBlocks(t testing.TB, ledger *testLedger, blocks ...block.Block) {
+	for _, blk := range blocks {
+		assertProcess(t, ledger, blk, ProcessProgress)
+		if _, err := ledger.GetBlock(blk.Hash()); err != nil {
+			t.Fatalf("block %s was not added: %s", blk.Hash(), err)
+		}
+	}
+}
+
+func assertProcess(t testing.TB, ledger *testLedger, blk block.Block, expected ProcessResult) {
+	res, err := ledger.AddBlock(blk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != expected {
+		t.Fatalf("unexpected result for block %s: %s != %s", blk.Hash(), res, expected)
+	}
+}
+
+func assertBalance(t testing.TB, ledger *testLedger, address nano.Address, expected nano.Balance) {
+	balance, err := ledger.GetBalance(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !balance.Equal(expected) {
+		t.Fatalf("unexpected balance for %s: %s != %s", address, balance, expected)
+	}
+}
+
+func assertWeight(t testing.TB, ledger *testLedger, address nano.Address, expected nano.Balance) {
+	var weight nano.Balance
+	err := ledger.store.View(func(txn StoreTxn) (err error) {
+		weight, err = txn.GetRepresentation(address)
+		return err
+	})
+	if err != nil {
 		t.Fatal(err)
 	}
+	if !weight.Equal(expected) {
+		t.Fatalf("unexpected weight for %s: %s != %s", address, weight, expected)
+	}
 }
 
 func parseBlocks(t *testing.T, filename string) (blocks []block.Block) {
@@ -110,8 +238,989 @@ func TestLedgerBlocks(t *testing.T) {
 
 	blocks := parseBlocks(t, "./testdata/blocks.json")
 	for _, blk := range blocks {
-		if err := ledger.AddBlock(blk); err != nil {
+		if _, err := ledger.AddBlock(blk); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLedgerRollback(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc1 := newTestAccount(t)
+	acc2 := newTestAccount(t)
+	rep := randomAddress(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+	genBalance := gen.Balance.Sub(amount)
+
+	// genesis -> acc1 (legacy), acc1 opens with a state block and sends half to
+	// acc2, which opens with a legacy open block
+	send1 := genAcc.send(gen.Block.Hash(), acc1.address, genBalance)
+	open1 := acc1.state(block.Hash{}, rep, amount, send1.Hash())
+	send2 := acc1.state(open1.Hash(), rep, nano.ParseBalanceInts(0, 500), block.Hash(acc2.address))
+	open2 := acc2.open(send2.Hash(), acc2.address)
+	mustAddBlocks(t, ledger, send1, open1, send2, open2)
+
+	assertBalance(t, ledger, genAcc.address, genBalance)
+	assertBalance(t, ledger, acc1.address, nano.ParseBalanceInts(0, 500))
+	assertBalance(t, ledger, acc2.address, nano.ParseBalanceInts(0, 500))
+	assertWeight(t, ledger, genAcc.address, genBalance)
+	assertWeight(t, ledger, rep, nano.ParseBalanceInts(0, 500))
+	assertWeight(t, ledger, acc2.address, nano.ParseBalanceInts(0, 500))
+
+	// rolling back the first send should undo everything that followed it
+	if err := ledger.Rollback(send1.Hash()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, blk := range []block.Block{send1, open1, send2, open2} {
+		if _, err := ledger.GetBlock(blk.Hash()); err != ErrNotFound {
+			t.Fatalf("block %s was not rolled back", blk.Hash())
+		}
+	}
+	for _, acc := range []*testAccount{acc1, acc2} {
+		if _, err := ledger.GetAddressInfo(acc.address); err != ErrNotFound {
+			t.Fatalf("account %s was not rolled back", acc.address)
+		}
+	}
+
+	assertBalance(t, ledger, genAcc.address, gen.Balance)
+	assertWeight(t, ledger, genAcc.address, gen.Balance)
+	assertWeight(t, ledger, rep, nano.ZeroBalance)
+	assertWeight(t, ledger, acc2.address, nano.ZeroBalance)
+
+	head, err := ledger.GetFrontier(genAcc.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != gen.Block.Hash() {
+		t.Fatalf("unexpected head block: %s", head)
+	}
+
+	count, err := ledger.CountBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("unexpected block count: %d", count)
+	}
+
+	// the chain can be added again after rolling back
+	mustAddBlocks(t, ledger, send1, open1)
+	assertBalance(t, ledger, acc1.address, amount)
+
+	if err := ledger.Rollback(gen.Block.Hash()); err != ErrRollbackGenesis {
+		t.Fatalf("unexpected error rolling back genesis: %v", err)
+	}
+}
+
+func TestLedgerRollbackReceive(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc := newTestAccount(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+
+	send1 := genAcc.send(gen.Block.Hash(), acc.address, gen.Balance.Sub(amount))
+	send2 := genAcc.send(send1.Hash(), acc.address, gen.Balance.Sub(amount).Sub(amount))
+	open := acc.open(send1.Hash(), acc.address)
+	receive := acc.receive(open.Hash(), send2.Hash())
+	mustAddBlocks(t, ledger, send1, send2, open, receive)
+	assertBalance(t, ledger, acc.address, amount.Add(amount))
+
+	// rolling back a receive puts the funds back in the pending list
+	if err := ledger.Rollback(receive.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	assertBalance(t, ledger, acc.address, amount)
+	assertWeight(t, ledger, acc.address, amount)
+
+	err := ledger.store.View(func(txn StoreTxn) error {
+		pending, err := txn.GetPending(acc.address, send2.Hash())
+		if err != nil {
+			return err
+		}
+		if pending.Address != genAcc.address || !pending.Amount.Equal(amount) {
+			t.Fatalf("unexpected pending entry: %+v", pending)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustAddBlocks(t, ledger, receive)
+	assertBalance(t, ledger, acc.address, amount.Add(amount))
+}
+
+func TestLedgerFork(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc1 := newTestAccount(t)
+	acc2 := newTestAccount(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+	balance := gen.Balance.Sub(amount)
+
+	// two sends of the same funds to different accounts
+	send1 := genAcc.state(gen.Block.Hash(), genAcc.address, balance, block.Hash(acc1.address))
+	send2 := genAcc.state(gen.Block.Hash(), genAcc.address, balance, block.Hash(acc2.address))
+	open1 := acc1.state(block.Hash{}, acc1.address, amount, send1.Hash())
+	mustAddBlocks(t, ledger, send1, open1)
+
+	assertProcess(t, ledger, send2, ProcessFork)
+
+	root := gen.Block.Hash()
+	forks, err := ledger.Forks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forks) != 2 || forks[0].Hash() != send1.Hash() || forks[1].Hash() != send2.Hash() {
+		t.Fatalf("unexpected list of forks: %v", forks)
+	}
+
+	// a vote for the block that is already in the ledger keeps it in place
+	votes := map[nano.Address]block.Hash{acc1.address: send2.Hash(), genAcc.address: send1.Hash()}
+	winner, err := ledger.ResolveFork(root, votes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if winner != send1.Hash() {
+		t.Fatalf("unexpected winner: %s", winner)
+	}
+	if _, err := ledger.Forks(root); err != ErrNoFork {
+		t.Fatalf("fork was not cleaned up: %v", err)
+	}
+
+	// the representative with the most weight votes for the other block
+	assertProcess(t, ledger, send2, ProcessFork)
+	votes = map[nano.Address]block.Hash{acc1.address: send1.Hash(), genAcc.address: send2.Hash()}
+	if winner, err = ledger.ResolveFork(root, votes); err != nil {
+		t.Fatal(err)
+	}
+	if winner != send2.Hash() {
+		t.Fatalf("unexpected winner: %s", winner)
+	}
+
+	if _, err := ledger.GetBlock(send1.Hash()); err != ErrNotFound {
+		t.Fatal("losing block was not rolled back")
+	}
+	if _, err := ledger.GetAddressInfo(acc1.address); err != ErrNotFound {
+		t.Fatal("receiver of the losing block was not rolled back")
+	}
+	if _, err := ledger.GetBlock(send2.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	assertBalance(t, ledger, genAcc.address, balance)
+	assertWeight(t, ledger, acc1.address, nano.ZeroBalance)
+}
+
+func TestLedgerResolveForkRejected(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc := newTestAccount(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+	fund := genAcc.send(gen.Block.Hash(), acc.address, gen.Balance.Sub(amount))
+	open := acc.open(fund.Hash(), acc.address)
+	send := acc.send(open.Hash(), randomAddress(t), amount.Sub(nano.ParseBalanceInts(0, 1)))
+	mustAddBlocks(t, ledger, fund, open, send)
+
+	// the fork is recorded before its balance is checked
+	negative := acc.send(open.Hash(), randomAddress(t), amount.Add(amount))
+	assertProcess(t, ledger, negative, ProcessFork)
+
+	root := open.Hash()
+	votes := map[nano.Address]block.Hash{acc.address: negative.Hash()}
+	if _, err := ledger.ResolveFork(root, votes); err == nil {
+		t.Fatal("expected an error resolving a fork in favor of an invalid block")
+	}
+
+	// the ledger and the fork are left untouched
+	if _, err := ledger.GetBlock(send.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.GetBlock(negative.Hash()); err != ErrNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if forks, err := ledger.Forks(root); err != nil || len(forks) != 2 {
+		t.Fatalf("unexpected forks: %v (err: %v)", forks, err)
+	}
+	assertBalance(t, ledger, acc.address, send.Balance)
+}
+
+func TestLedgerForkSignature(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	amount := nano.ParseBalanceInts(0, 1000)
+	balance := gen.Balance.Sub(amount)
+	send := genAcc.send(gen.Block.Hash(), randomAddress(t), balance)
+	mustAddBlocks(t, ledger, send)
+
+	// a competing legacy block that wasn't signed by the owner of the account
+	// must not be recorded as a fork
+	other := newTestAccount(t)
+	unsigned := []block.Block{
+		other.send(gen.Block.Hash(), other.address, balance),
+		other.receive(gen.Block.Hash(), send.Hash()),
+		&block.ChangeBlock{PreviousHash: gen.Block.Hash(), Representative: other.address},
+	}
+	for _, blk := range unsigned {
+		assertProcess(t, ledger, blk, ProcessBadSignature)
+	}
+	if _, err := ledger.Forks(gen.Block.Hash()); err != ErrNoFork {
+		t.Fatalf("unexpected fork: %v", err)
+	}
+
+	fork := genAcc.send(gen.Block.Hash(), other.address, balance)
+	assertProcess(t, ledger, fork, ProcessFork)
+}
+
+func TestLedgerVerifyBlock(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	other := newTestAccount(t)
+	balance := gen.Balance.Sub(nano.ParseBalanceInts(0, 1000))
+
+	// a state block that claims to belong to an account it wasn't signed by
+	forged := other.state(gen.Block.Hash(), genAcc.address, balance, block.Hash(other.address))
+	forged.Address = genAcc.address
+
+	tests := []struct {
+		blk block.Block
+		err error
+	}{
+		{genAcc.send(gen.Block.Hash(), other.address, balance), nil},
+		{other.send(gen.Block.Hash(), other.address, balance), ErrBadSignature},
+		{genAcc.send(randomHash(t), other.address, balance), ErrMissingPrevious},
+		{genAcc.state(gen.Block.Hash(), genAcc.address, balance, block.Hash(other.address)), nil},
+		{genAcc.state(randomHash(t), genAcc.address, balance, block.Hash(other.address)), nil},
+		{forged, ErrBadSignature},
+		{other.open(randomHash(t), other.address), nil},
+	}
+
+	for i, test := range tests {
+		if err := ledger.VerifyBlock(test.blk); err != test.err {
+			t.Fatalf("unexpected result for block %d: %v != %v", i, err, test.err)
+		}
+	}
+}
+
+func TestLedgerConfirm(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	send := genAcc.state(gen.Block.Hash(), genAcc.address, gen.Balance.Sub(nano.ParseBalanceInts(0, 1)), block.Hash(randomAddress(t)))
+	mustAddBlocks(t, ledger, send)
+
+	if confirmed, err := ledger.Confirmed(send.Hash()); err != nil || confirmed {
+		t.Fatalf("block should not be confirmed yet (err: %v)", err)
+	}
+	if err := ledger.Confirm(send.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	if confirmed, err := ledger.Confirmed(send.Hash()); err != nil || !confirmed {
+		t.Fatalf("block should be confirmed (err: %v)", err)
+	}
+
+	if err := ledger.Rollback(send.Hash()); err != ErrRollbackConfirmed {
+		t.Fatalf("unexpected error rolling back a confirmed block: %v", err)
+	}
+	if err := ledger.Confirm(randomHash(t)); err != ErrNotFound {
+		t.Fatalf("unexpected error confirming an unknown block: %v", err)
+	}
+}
+
+func TestLedgerVoteSequence(t *testing.T) {
+	ledger := initTestLedger(t)
+	defer ledger.Close(t)
+
+	rep := randomAddress(t)
+	for i := uint64(1); i <= 3; i++ {
+		sequence, err := ledger.NextVoteSequence(rep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sequence != i {
+			t.Fatalf("unexpected sequence: %d != %d", sequence, i)
+		}
+	}
+}
+
+func TestLedgerProcessResults(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc := newTestAccount(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+	balance := gen.Balance.Sub(amount)
+
+	send := genAcc.state(gen.Block.Hash(), genAcc.address, balance, block.Hash(acc.address))
+	assertProcess(t, ledger, send, ProcessProgress)
+	assertProcess(t, ledger, send, ProcessOld)
+
+	// a block signed by the wrong account
+	badSig := genAcc.state(send.Hash(), genAcc.address, balance.Sub(amount), block.Hash(acc.address))
+	badSig.Signature = acc.state(send.Hash(), genAcc.address, balance.Sub(amount), block.Hash(acc.address)).Signature
+	assertProcess(t, ledger, badSig, ProcessBadSignature)
+
+	// spending more than the balance of the account
+	legacySend := genAcc.send(send.Hash(), acc.address, gen.Balance)
+	assertProcess(t, ledger, legacySend, ProcessNegativeSpend)
+
+	// an unchanged balance with a link
+	zeroSpend := genAcc.state(send.Hash(), genAcc.address, balance, block.Hash(acc.address))
+	assertProcess(t, ledger, zeroSpend, ProcessBalanceMismatch)
+
+	// receiving more than was sent
+	open := acc.state(block.Hash{}, acc.address, amount.Add(amount), send.Hash())
+	assertProcess(t, ledger, open, ProcessBalanceMismatch)
+
+	// receiving funds that were sent to another account
+	other := newTestAccount(t)
+	assertProcess(t, ledger, other.state(block.Hash{}, other.address, amount, send.Hash()), ProcessUnreceivable)
+
+	// unknown previous and source blocks
+	assertProcess(t, ledger, acc.state(randomHash(t), acc.address, amount, block.Hash{}), ProcessGapPrevious)
+	assertProcess(t, ledger, acc.open(randomHash(t), acc.address), ProcessGapSource)
+
+	// a block that builds on the chain of another account
+	assertProcess(t, ledger, acc.state(send.Hash(), acc.address, nano.ZeroBalance, block.Hash{}), ProcessBlockPosition)
+
+	// a competing block for the same root
+	fork := genAcc.state(gen.Block.Hash(), genAcc.address, balance, block.Hash(other.address))
+	assertProcess(t, ledger, fork, ProcessFork)
+
+	open = acc.state(block.Hash{}, acc.address, amount, send.Hash())
+	assertProcess(t, ledger, open, ProcessProgress)
+	assertBalance(t, ledger, acc.address, amount)
+}
+
+func TestLedgerSideband(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	amount := nano.ParseBalanceInts(0, 1000)
+	send1 := genAcc.state(gen.Block.Hash(), genAcc.address, gen.Balance.Sub(amount), block.Hash(randomAddress(t)))
+	send2 := genAcc.state(send1.Hash(), genAcc.address, gen.Balance.Sub(amount).Sub(amount), block.Hash(randomAddress(t)))
+	mustAddBlocks(t, ledger, send1, send2)
+
+	assertSideband := func(hash block.Hash, height uint64, successor block.Hash, balance nano.Balance) {
+		sideband, err := ledger.GetSideband(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sideband.Height != height || sideband.Successor != successor || sideband.Account != genAcc.address || !sideband.Balance.Equal(balance) {
+			t.Fatalf("unexpected sideband for %s: %+v", hash, sideband)
+		}
+	}
+
+	assertSideband(gen.Block.Hash(), 1, send1.Hash(), gen.Balance)
+	assertSideband(send1.Hash(), 2, send2.Hash(), send1.Balance)
+	assertSideband(send2.Hash(), 3, block.Hash{}, send2.Balance)
+
+	info, err := ledger.GetAddressInfo(genAcc.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.BlockCount != 3 {
+		t.Fatalf("unexpected block count: %d", info.BlockCount)
+	}
+
+	if err := ledger.Rollback(send2.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	assertSideband(send1.Hash(), 2, block.Hash{}, send1.Balance)
+	if _, err := ledger.GetSideband(send2.Hash()); err != ErrNotFound {
+		t.Fatalf("sideband was not removed: %v", err)
+	}
+
+	if info, err = ledger.GetAddressInfo(genAcc.address); err != nil {
+		t.Fatal(err)
+	}
+	if info.BlockCount != 2 {
+		t.Fatalf("unexpected block count after rollback: %d", info.BlockCount)
+	}
+}
+
+func TestLedgerAccountHistory(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc := newTestAccount(t)
+	rep := randomAddress(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+
+	send1 := genAcc.state(gen.Block.Hash(), genAcc.address, gen.Balance.Sub(amount), block.Hash(acc.address))
+	send2 := genAcc.send(send1.Hash(), acc.address, gen.Balance.Sub(amount).Sub(amount))
+	open := acc.state(block.Hash{}, acc.address, amount, send1.Hash())
+	change := acc.state(open.Hash(), rep, amount, block.Hash{})
+	receive := acc.state(change.Hash(), rep, amount.Add(amount), send2.Hash())
+	mustAddBlocks(t, ledger, send1, send2, open, change, receive)
+
+	type expectedEntry struct {
+		hash    block.Hash
+		subtype block.Subtype
+		account nano.Address
+		amount  nano.Balance
+		height  uint64
+	}
+	assertHistory := func(entries []*HistoryEntry, expected ...expectedEntry) {
+		if len(entries) != len(expected) {
+			t.Fatalf("unexpected amount of entries: %d != %d", len(entries), len(expected))
+		}
+		for i, entry := range entries {
+			e := expected[i]
+			if entry.Hash != e.hash || entry.Subtype != e.subtype || entry.Account != e.account || !entry.Amount.Equal(e.amount) || entry.Height != e.height {
+				t.Fatalf("unexpected entry %d: %+v", i, entry)
+			}
+		}
+	}
+
+	entries, err := ledger.AccountHistory(acc.address, block.Hash{}, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHistory(entries,
+		expectedEntry{receive.Hash(), block.SubtypeReceive, genAcc.address, amount, 3},
+		expectedEntry{change.Hash(), block.SubtypeChange, rep, nano.ZeroBalance, 2},
+		expectedEntry{open.Hash(), block.SubtypeOpen, genAcc.address, amount, 1},
+	)
+
+	// page through the chain of the genesis account from old to new
+	entries, err = ledger.AccountHistory(genAcc.address, block.Hash{}, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHistory(entries,
+		expectedEntry{gen.Block.Hash(), block.SubtypeOpen, genAcc.address, gen.Balance, 1},
+		expectedEntry{send1.Hash(), block.SubtypeSend, acc.address, amount, 2},
+	)
+
+	entries, err = ledger.AccountHistory(genAcc.address, send2.Hash(), 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHistory(entries, expectedEntry{send2.Hash(), block.SubtypeSend, acc.address, amount, 3})
+
+	if _, err := ledger.AccountHistory(acc.address, send1.Hash(), 1, false); err != ErrNotInChain {
+		t.Fatalf("expected an error for a block of another account, got: %v", err)
+	}
+}
+
+func TestLedgerPending(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc := newTestAccount(t)
+	amounts := []nano.Balance{
+		nano.ParseBalanceInts(0, 10),
+		nano.ParseBalanceInts(0, 30),
+		nano.ParseBalanceInts(0, 20),
+	}
+
+	balance := gen.Balance
+	previous := gen.Block.Hash()
+	sends := map[block.Hash]nano.Balance{}
+	for _, amount := range amounts {
+		balance = balance.Sub(amount)
+		send := genAcc.state(previous, genAcc.address, balance, block.Hash(acc.address))
+		mustAddBlocks(t, ledger, send)
+		sends[send.Hash()] = amount
+		previous = send.Hash()
+	}
+
+	entries, err := ledger.Pending(acc.address, nano.ZeroBalance, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(amounts) {
+		t.Fatalf("unexpected amount of pending entries: %d", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.Source != genAcc.address || !entry.Amount.Equal(sends[entry.Hash]) {
+			t.Fatalf("unexpected pending entry: %+v", entry)
+		}
+	}
+
+	entries, err = ledger.Pending(acc.address, nano.ParseBalanceInts(0, 15), 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !entries[0].Amount.Equal(amounts[1]) {
+		t.Fatalf("unexpected pending entries: %+v", entries)
+	}
+
+	entries, err = ledger.Pending(acc.address, nano.ParseBalanceInts(0, 15), 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || !entries[0].Amount.Equal(amounts[1]) || !entries[1].Amount.Equal(amounts[2]) {
+		t.Fatalf("unexpected pending entries: %+v", entries)
+	}
+}
+
+func TestLedgerRepresentatives(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc1 := newTestAccount(t)
+	acc2 := newTestAccount(t)
+	rep := randomAddress(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+
+	send1 := genAcc.state(gen.Block.Hash(), genAcc.address, gen.Balance.Sub(amount), block.Hash(acc1.address))
+	send2 := genAcc.state(send1.Hash(), genAcc.address, gen.Balance.Sub(amount).Sub(amount), block.Hash(acc2.address))
+	open1 := acc1.state(block.Hash{}, rep, amount, send1.Hash())
+	open2 := acc2.state(block.Hash{}, rep, amount, send2.Hash())
+	mustAddBlocks(t, ledger, send1, send2, open1, open2)
+
+	reps, total, err := ledger.Representatives()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 2 {
+		t.Fatalf("unexpected amount of representatives: %d", len(reps))
+	}
+	if reps[0].Address != genAcc.address || !reps[0].Weight.Equal(send2.Balance) || reps[0].Delegators != 1 {
+		t.Fatalf("unexpected representative: %+v", reps[0])
+	}
+	if reps[1].Address != rep || !reps[1].Weight.Equal(amount.Add(amount)) || reps[1].Delegators != 2 {
+		t.Fatalf("unexpected representative: %+v", reps[1])
+	}
+	if !total.Equal(gen.Balance) {
+		t.Fatalf("unexpected total weight: %s", total)
+	}
+}
+
+func TestLedgerMigrate(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc := newTestAccount(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+	send1 := genAcc.send(gen.Block.Hash(), acc.address, gen.Balance.Sub(amount))
+	open := acc.open(send1.Hash(), acc.address)
+	send2 := genAcc.send(send1.Hash(), acc.address, send1.Balance.Sub(amount))
+	receive := acc.receive(open.Hash(), send2.Hash())
+	send3 := acc.state(receive.Hash(), acc.address, amount, block.Hash(genAcc.address))
+	receive2 := genAcc.state(send2.Hash(), genAcc.address, send2.Balance.Add(amount), send3.Hash())
+	mustAddBlocks(t, ledger, send1, open, send2, receive, send3, receive2)
+
+	sidebands := map[block.Hash]*Sideband{}
+	err := ledger.store.View(func(txn StoreTxn) error {
+		return txn.WalkBlocks(func(blk block.Block) error {
+			sideband, err := txn.GetSideband(blk.Hash())
+			if err != nil {
+				return err
+			}
+
+			sideband.Timestamp = 0
+			sidebands[blk.Hash()] = sideband
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// turn the database into one with schema version 0
+	err = ledger.store.Update(func(txn StoreTxn) error {
+		for hash := range sidebands {
+			if err := txn.DeleteSideband(hash); err != nil {
+				return err
+			}
+		}
+
+		for _, address := range []nano.Address{genAcc.address, acc.address} {
+			info, err := txn.GetAddress(address)
+			if err != nil {
+				return err
+			}
+
+			info.BlockCount = 0
+			if err := txn.UpdateAddress(address, info); err != nil {
+				return err
+			}
+		}
+
+		return txn.SetVersion(0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewLedger(ledger.store, LedgerOptions{Genesis: gen}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = ledger.store.View(func(txn StoreTxn) error {
+		version, err := txn.GetVersion()
+		if err != nil {
+			return err
+		}
+		if version != SchemaVersion {
+			t.Fatalf("unexpected version: %d", version)
+		}
+
+		for hash, expected := range sidebands {
+			sideband, err := txn.GetSideband(hash)
+			if err != nil {
+				return err
+			}
+			if *sideband != *expected {
+				t.Fatalf("unexpected sideband for %s: %+v != %+v", hash, sideband, expected)
+			}
+		}
+
+		for address, count := range map[nano.Address]uint64{genAcc.address: 4, acc.address: 3} {
+			info, err := txn.GetAddress(address)
+			if err != nil {
+				return err
+			}
+			if info.BlockCount != count {
+				t.Fatalf("unexpected block count for %s: %d", address, info.BlockCount)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// refuse to open databases of newer versions
+	err = ledger.store.Update(func(txn StoreTxn) error {
+		return txn.SetVersion(SchemaVersion + 1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLedger(ledger.store, LedgerOptions{Genesis: gen}); err != ErrNewerSchema {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLedgerMigrateWeights(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc := newTestAccount(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+	send := genAcc.send(gen.Block.Hash(), acc.address, gen.Balance.Sub(amount))
+	open := acc.open(send.Hash(), acc.address)
+	mustAddBlocks(t, ledger, send, open)
+
+	// turn the database into one with schema version 2, which stored the
+	// weights in little-endian byte order
+	err := ledger.store.Update(func(txn StoreTxn) error {
+		weights := map[nano.Address]nano.Balance{genAcc.address: send.Balance, acc.address: amount}
+		for address, weight := range weights {
+			var key [1 + nano.AddressSize]byte
+			key[0] = idPrefixRepresentation
+			copy(key[1:], address[:])
+
+			if err := txn.(*kvStoreTxn).kv.set(key[:], weight.Bytes(binary.LittleEndian), 0); err != nil {
+				return err
+			}
+		}
+
+		return txn.SetVersion(2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewLedger(ledger.store, LedgerOptions{Genesis: gen}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertWeight(t, ledger, genAcc.address, send.Balance)
+	assertWeight(t, ledger, acc.address, amount)
+}
+
+func TestMigrationOrder(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != uint64(i+1) {
+			t.Fatalf("migration %q has version %d, expected %d", m.name, m.version, i+1)
+		}
+	}
+	if len(migrations) != SchemaVersion {
+		t.Fatalf("the last migration doesn't upgrade to version %d", SchemaVersion)
+	}
+}
+
+func TestLedgerSnapshot(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	signer := newTestAccount(t)
+	var link block.Hash
+	copy(link[:], "epoch v1 block")
+	gen.Epochs = map[block.Epoch]genesis.Epoch{
+		block.Epoch1: {Link: link, Signer: signer.address},
+	}
+
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc := newTestAccount(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+	send1 := genAcc.send(gen.Block.Hash(), acc.address, gen.Balance.Sub(amount))
+	open := acc.open(send1.Hash(), acc.address)
+	send2 := genAcc.send(send1.Hash(), acc.address, send1.Balance.Sub(amount))
+	receive := acc.receive(open.Hash(), send2.Hash())
+	send3 := acc.state(receive.Hash(), acc.address, amount, block.Hash(genAcc.address))
+	receive2 := genAcc.state(send2.Hash(), genAcc.address, send2.Balance.Add(amount), send3.Hash())
+	mustAddBlocks(t, ledger, send1, open, send2, receive, send3, receive2)
+
+	// epoch open blocks have to come after a send to their account, even if
+	// their account comes first
+	epochOpen := func(address nano.Address) *block.StateBlock {
+		blk := block.StateBlock{Address: address, Balance: nano.ZeroBalance, Link: link}
+		signer.sign(&blk.Signature, blk.Hash())
+		return &blk
+	}
+	newLowerAccount := func() *testAccount {
+		for {
+			acc := newTestAccount(t)
+			if bytes.Compare(acc.address[:], genAcc.address[:]) < 0 {
+				return acc
+			}
+		}
+	}
+	pendingAcc, receivedAcc := newLowerAccount(), newLowerAccount()
+	send4 := genAcc.state(receive2.Hash(), genAcc.address, receive2.Balance.Sub(amount), block.Hash(pendingAcc.address))
+	send5 := genAcc.state(send4.Hash(), genAcc.address, send4.Balance.Sub(amount), block.Hash(receivedAcc.address))
+	open2 := epochOpen(pendingAcc.address)
+	open3 := epochOpen(receivedAcc.address)
+	receive3 := receivedAcc.state(open3.Hash(), receivedAcc.address, amount, send5.Hash())
+	mustAddBlocks(t, ledger, send4, send5, open2, open3, receive3)
+
+	var buf bytes.Buffer
+	count, err := ledger.ExportSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 12 {
+		t.Fatalf("unexpected amount of exported blocks: %d", count)
+	}
+	snapshot := buf.Bytes()
+
+	// the genesis block comes first
+	if snapshot[len(snapshotMagic)+1] != gen.Block.ID() {
+		t.Fatal("genesis block is not the first block of the snapshot")
+	}
+
+	imported := initTestLedgerGenesis(t, gen)
+	defer imported.Close(t)
+
+	// nothing is imported if the checksum doesn't match
+	snapshot[len(snapshot)-1] ^= 0xff
+	if _, err := imported.ImportSnapshot(bytes.NewReader(snapshot)); err != ErrSnapshotChecksum {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count, err := imported.CountBlocks(); err != nil || count != 1 {
+		t.Fatalf("blocks were imported despite the bad checksum: %d (err: %v)", count, err)
+	}
+	snapshot[len(snapshot)-1] ^= 0xff
+
+	if count, err = imported.ImportSnapshot(bytes.NewReader(snapshot)); err != nil {
+		t.Fatal(err)
+	}
+	if count != 12 {
+		t.Fatalf("unexpected amount of imported blocks: %d", count)
+	}
+
+	assertBalance(t, imported, genAcc.address, send5.Balance)
+	assertBalance(t, imported, acc.address, send3.Balance)
+	assertBalance(t, imported, receivedAcc.address, amount)
+	for _, hash := range []block.Hash{receive2.Hash(), open2.Hash(), receive3.Hash()} {
+		if _, err := imported.GetBlock(hash); err != nil {
 			t.Fatal(err)
 		}
 	}
+
+	if _, err := imported.ImportSnapshot(bytes.NewReader([]byte("not a snapshot"))); err != ErrBadSnapshot {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLedgerCheck(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	acc := newTestAccount(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+	send1 := genAcc.send(gen.Block.Hash(), acc.address, gen.Balance.Sub(amount))
+	open := acc.open(send1.Hash(), acc.address)
+	send2 := genAcc.state(send1.Hash(), genAcc.address, send1.Balance.Sub(amount), block.Hash(acc.address))
+	mustAddBlocks(t, ledger, send1, open, send2)
+
+	assertIssues := func(repair bool, kinds ...string) {
+		issues, err := ledger.Check(repair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) != len(kinds) {
+			t.Fatalf("unexpected issues: %v", issues)
+		}
+		for i, issue := range issues {
+			if issue.Kind != kinds[i] {
+				t.Fatalf("unexpected issue: %s", issue)
+			}
+		}
+	}
+	assertIssues(false)
+
+	err := ledger.store.Update(func(txn StoreTxn) error {
+		info, err := txn.GetAddress(acc.address)
+		if err != nil {
+			return err
+		}
+		info.Balance = nano.ZeroBalance
+		if err := txn.UpdateAddress(acc.address, info); err != nil {
+			return err
+		}
+
+		if err := txn.DeletePending(acc.address, send2.Hash()); err != nil {
+			return err
+		}
+		if err := txn.AddFrontier(&block.Frontier{Address: acc.address, Hash: randomHash(t)}); err != nil {
+			return err
+		}
+		return txn.AddRepresentation(acc.address, amount)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := []string{CheckIssueAccount, CheckIssueFrontier, CheckIssuePending, CheckIssueWeight}
+	assertIssues(false, kinds...)
+	assertIssues(true, kinds...)
+	assertIssues(false)
+
+	assertBalance(t, ledger, acc.address, amount)
+	assertWeight(t, ledger, acc.address, amount)
+}
+
+func TestLedgerEpoch(t *testing.T) {
+	gen, genAcc := newTestGenesis(t)
+	signer := newTestAccount(t)
+
+	var link1, link2 block.Hash
+	copy(link1[:], "epoch v1 block")
+	copy(link2[:], "epoch v2 block")
+	gen.Epochs = map[block.Epoch]genesis.Epoch{
+		block.Epoch1: {Link: link1, Signer: signer.address},
+		block.Epoch2: {Link: link2, Signer: signer.address},
+	}
+	// no work value meets the send threshold of the second epoch
+	gen.WorkThresholds.Epochs = map[block.Epoch]block.EpochThresholds{
+		block.Epoch2: {Send: ^uint64(0)},
+	}
+
+	ledger := initTestLedgerGenesis(t, gen)
+	defer ledger.Close(t)
+
+	epochBlock := func(address nano.Address, previous block.Hash, rep nano.Address, balance nano.Balance, link block.Hash) *block.StateBlock {
+		blk := block.StateBlock{
+			Address:        address,
+			PreviousHash:   previous,
+			Representative: rep,
+			Balance:        balance,
+			Link:           link,
+		}
+		signer.sign(&blk.Signature, blk.Hash())
+		return &blk
+	}
+	assertEpoch := func(address nano.Address, expected block.Epoch) {
+		info, err := ledger.GetAddressInfo(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Epoch != expected {
+			t.Fatalf("unexpected epoch for %s: %s != %s", address, info.Epoch, expected)
+		}
+	}
+
+	// only the epoch signer can upgrade accounts, one epoch at a time
+	assertProcess(t, ledger, genAcc.state(gen.Block.Hash(), genAcc.address, gen.Balance, link1), ProcessBadSignature)
+	assertProcess(t, ledger, epochBlock(genAcc.address, gen.Block.Hash(), genAcc.address, gen.Balance, link2), ProcessBlockPosition)
+	assertProcess(t, ledger, epochBlock(genAcc.address, gen.Block.Hash(), randomAddress(t), gen.Balance, link1), ProcessRepresentativeMismatch)
+
+	epoch1 := epochBlock(genAcc.address, gen.Block.Hash(), genAcc.address, gen.Balance, link1)
+	mustAddBlocks(t, ledger, epoch1)
+	assertEpoch(genAcc.address, block.Epoch1)
+
+	// legacy blocks can't follow an epoch block
+	acc := newTestAccount(t)
+	amount := nano.ParseBalanceInts(0, 1000)
+	assertProcess(t, ledger, genAcc.send(epoch1.Hash(), acc.address, gen.Balance.Sub(amount)), ProcessBlockPosition)
+
+	send := genAcc.state(epoch1.Hash(), genAcc.address, gen.Balance.Sub(amount), block.Hash(acc.address))
+	mustAddBlocks(t, ledger, send)
+
+	// funds sent by upgraded accounts can't be received with legacy blocks
+	assertProcess(t, ledger, acc.open(send.Hash(), acc.address), ProcessUnreceivable)
+
+	// epoch open blocks only work for accounts with pending funds
+	assertProcess(t, ledger, epochBlock(randomAddress(t), block.Hash{}, nano.Address{}, nano.ZeroBalance, link2), ProcessGapEpochOpenPending)
+	assertProcess(t, ledger, epochBlock(acc.address, block.Hash{}, nano.Address{}, amount, link2), ProcessBalanceMismatch)
+	assertProcess(t, ledger, epochBlock(acc.address, block.Hash{}, acc.address, nano.ZeroBalance, link2), ProcessRepresentativeMismatch)
+
+	open := epochBlock(acc.address, block.Hash{}, nano.Address{}, nano.ZeroBalance, link2)
+	receive := acc.state(open.Hash(), acc.address, amount, send.Hash())
+	mustAddBlocks(t, ledger, open, receive)
+	assertEpoch(acc.address, block.Epoch2)
+	assertBalance(t, ledger, acc.address, amount)
+	assertWeight(t, ledger, acc.address, amount)
+
+	// sends of accounts in the second epoch use a different work threshold
+	assertProcess(t, ledger, acc.state(receive.Hash(), acc.address, nano.ZeroBalance, block.Hash(genAcc.address)), ProcessBadWork)
+
+	entries, err := ledger.AccountHistory(genAcc.address, epoch1.Hash(), 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Subtype != block.SubtypeEpoch || entries[0].Account != signer.address {
+		t.Fatalf("unexpected history entry: %+v", entries[0])
+	}
+
+	issues, err := ledger.Check(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("unexpected issues: %v", issues)
+	}
+
+	// rolling back the epoch block downgrades the account again
+	if err := ledger.Rollback(epoch1.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	assertEpoch(genAcc.address, block.Epoch0)
+	assertBalance(t, ledger, acc.address, nano.ZeroBalance)
+
+	if issues, err = ledger.Check(false); err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("unexpected issues after rollback: %v", issues)
+	}
 }
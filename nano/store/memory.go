@@ -0,0 +1,84 @@
+package store
+
+import (
+	"sync"
+)
+
+// MemoryStore represents a Nano block lattice store that keeps everything in
+// memory. It's meant for tests and tools that don't need to persist the
+// ledger.
+type MemoryStore struct {
+	mutex sync.RWMutex
+	table *memoryTable
+}
+
+// memoryTable is an ordered key/value table.
+type memoryTable struct {
+	items map[string]*kvItem
+	keys  *keyIndex
+}
+
+// NewMemoryStore creates a new empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{table: newMemoryTable()}
+}
+
+// Close closes the store. The contents of the store are lost.
+func (s *MemoryStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.table = newMemoryTable()
+	return nil
+}
+
+func (s *MemoryStore) View(fn func(txn StoreTxn) error) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return fn(&kvStoreTxn{kv: newCowTxn(s.table)})
+}
+
+func (s *MemoryStore) Update(fn func(txn StoreTxn) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t := newCowTxn(s.table)
+	if err := fn(&kvStoreTxn{kv: t}); err != nil {
+		return err
+	}
+
+	for key, item := range t.writes {
+		if item == nil {
+			delete(s.table.items, key)
+			s.table.keys.delete(key)
+		} else {
+			s.table.items[key] = item
+			s.table.keys.insert(key)
+		}
+	}
+
+	return nil
+}
+
+func newMemoryTable() *memoryTable {
+	return &memoryTable{items: map[string]*kvItem{}, keys: newKeyIndex()}
+}
+
+func (m *memoryTable) lookup(key string) (*kvItem, error) {
+	item, ok := m.items[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return item, nil
+}
+
+func (m *memoryTable) contains(key string) bool {
+	_, ok := m.items[key]
+	return ok
+}
+
+func (m *memoryTable) seek(key string) *keyNode {
+	return m.keys.seek(key)
+}
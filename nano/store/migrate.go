@@ -0,0 +1,225 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+const (
+	// SchemaVersion is the version of the database layout used by this version
+	// of the ledger.
+	SchemaVersion = 3
+)
+
+var (
+	ErrNewerSchema = errors.New("the database was created by a newer version of gonano")
+)
+
+// migration upgrades the database from the previous schema version to version.
+// Migrations may flush the transaction, so they must be safe to run again
+// after an interruption.
+type migration struct {
+	version uint64
+	name    string
+	migrate func(l *Ledger, txn StoreTxn) error
+}
+
+var (
+	// migrations is the list of migrations, ordered by version
+	migrations = []migration{
+		{version: 1, name: "add sidebands and block counts", migrate: (*Ledger).migrateSidebands},
+		{version: 2, name: "add account epochs", migrate: (*Ledger).migrateEpochs},
+		{version: 3, name: "recompute representative weights", migrate: (*Ledger).migrateWeights},
+	}
+)
+
+// migrate upgrades the database to SchemaVersion one version at a time.
+func (l *Ledger) migrate() error {
+	var version uint64
+	err := l.db.View(func(txn StoreTxn) (err error) {
+		version, err = txn.GetVersion()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if version > SchemaVersion {
+		return ErrNewerSchema
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+
+		err := l.db.Update(func(txn StoreTxn) error {
+			if err := m.migrate(l, txn); err != nil {
+				return err
+			}
+
+			return txn.SetVersion(m.version)
+		})
+		if err != nil {
+			return fmt.Errorf("migration to version %d failed: %s", m.version, err)
+		}
+
+		version = m.version
+	}
+
+	return nil
+}
+
+// migrateSidebands adds the sideband of every block and the block count of
+// every account. The timestamps of the sidebands are left at zero, as the time
+// the blocks were added is unknown.
+func (l *Ledger) migrateSidebands(txn StoreTxn) error {
+	type account struct {
+		address nano.Address
+		info    *AddressInfo
+	}
+
+	// the address info is updated below, so collect everything first
+	var accounts []account
+	err := txn.WalkAddresses(func(address nano.Address, info *AddressInfo) error {
+		accounts = append(accounts, account{address: address, info: info})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	balances := map[block.Hash]nano.Balance{}
+	for _, acc := range accounts {
+		// walk the chain back from the head block
+		var chain []block.Hash
+		for hash := acc.info.HeadBlock; !hash.IsZero(); {
+			blk, err := txn.GetBlock(hash)
+			if err != nil {
+				return err
+			}
+
+			chain = append(chain, hash)
+			hash = blk.Previous()
+		}
+
+		for i := range chain {
+			hash := chain[len(chain)-1-i]
+			balance, err := l.computeBalance(txn, hash, balances)
+			if err != nil {
+				return err
+			}
+
+			sideband := Sideband{
+				Height:  uint64(i + 1),
+				Account: acc.address,
+				Balance: balance,
+			}
+			if i < len(chain)-1 {
+				sideband.Successor = chain[len(chain)-2-i]
+			}
+
+			if err := txn.UpdateSideband(hash, &sideband); err != nil {
+				return err
+			}
+		}
+
+		acc.info.BlockCount = uint64(len(chain))
+		if err := txn.UpdateAddress(acc.address, acc.info); err != nil {
+			return err
+		}
+
+		if err := txn.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateEpochs rewrites the account info, pending transactions and sidebands
+// in the layout that includes the epoch. Databases with an older schema
+// version only have accounts in the first epoch, which is what the missing
+// epoch is decoded as.
+func (l *Ledger) migrateEpochs(txn StoreTxn) error {
+	infos := map[nano.Address]*AddressInfo{}
+	err := txn.WalkAddresses(func(address nano.Address, info *AddressInfo) error {
+		infos[address] = info
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for address, info := range infos {
+		if err := txn.UpdateAddress(address, info); err != nil {
+			return err
+		}
+		if err := txn.Flush(); err != nil {
+			return err
+		}
+	}
+
+	pending := map[pendingKey]*Pending{}
+	err = txn.WalkAllPending(func(destination nano.Address, hash block.Hash, p *Pending) error {
+		pending[pendingKey{destination: destination, hash: hash}] = p
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, p := range pending {
+		if err := txn.DeletePending(key.destination, key.hash); err != nil {
+			return err
+		}
+		if err := txn.AddPending(key.destination, key.hash, p); err != nil {
+			return err
+		}
+		if err := txn.Flush(); err != nil {
+			return err
+		}
+	}
+
+	var hashes []block.Hash
+	err = txn.WalkBlocks(func(blk block.Block) error {
+		hashes = append(hashes, blk.Hash())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		sideband, err := txn.GetSideband(hash)
+		if err != nil {
+			return err
+		}
+		if err := txn.UpdateSideband(hash, sideband); err != nil {
+			return err
+		}
+		if err := txn.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateWeights recomputes the voting weight of every representative. Older
+// schema versions wrote the weights in little-endian byte order, but read them
+// back in big-endian order. Every update made the stored weights more wrong, so
+// rather than converting them, they're computed from the account chains.
+func (l *Ledger) migrateWeights(txn StoreTxn) error {
+	report := func(kind string, format string, a ...interface{}) {}
+
+	state, err := l.computeState(txn, report)
+	if err != nil {
+		return err
+	}
+
+	return l.checkWeights(txn, state, true, report)
+}
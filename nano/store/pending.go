@@ -3,6 +3,7 @@ package store
 import (
 	"bytes"
 	"encoding/binary"
+	"sort"
 
 	"github.com/alexbakker/gonano/nano"
 	"github.com/alexbakker/gonano/nano/block"
@@ -18,6 +19,8 @@ type PendingKey [PendingKeySize]byte
 type Pending struct {
 	Address nano.Address
 	Amount  nano.Balance
+	// Epoch is the epoch of the sending account at the time of the send.
+	Epoch block.Epoch
 }
 
 // MarshalBinary implements the encoding.BinaryMarshaler interface.
@@ -33,6 +36,10 @@ func (p *Pending) MarshalBinary() ([]byte, error) {
 		return nil, err
 	}
 
+	if err = buf.WriteByte(byte(p.Epoch)); err != nil {
+		return nil, err
+	}
+
 	return buf.Bytes(), nil
 }
 
@@ -52,5 +59,65 @@ func (p *Pending) UnmarshalBinary(data []byte) error {
 		return err
 	}
 
+	// the epoch is missing in databases with schema version 1 and older
+	p.Epoch = block.Epoch0
+	if reader.Len() > 0 {
+		epoch, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		p.Epoch = block.Epoch(epoch)
+	}
+
 	return util.AssertReaderEOF(reader)
 }
+
+// PendingEntry describes a send block that has not been received yet.
+type PendingEntry struct {
+	Hash   block.Hash
+	Source nano.Address
+	Amount nano.Balance
+}
+
+// Pending returns up to count pending transactions of the given address with
+// an amount of at least threshold. If count is zero, all of them are returned.
+// Entries are ordered by hash, unless sortByAmount is set, in which case the
+// largest amounts come first.
+func (l *Ledger) Pending(address nano.Address, threshold nano.Balance, count int, sortByAmount bool) ([]*PendingEntry, error) {
+	var entries []*PendingEntry
+
+	err := l.db.View(func(txn StoreTxn) error {
+		return txn.WalkPending(address, func(hash block.Hash, pending *Pending) error {
+			if pending.Amount.Compare(threshold) == nano.BalanceCompSmaller {
+				return nil
+			}
+
+			entries = append(entries, &PendingEntry{
+				Hash:   hash,
+				Source: pending.Address,
+				Amount: pending.Amount,
+			})
+
+			// all entries are needed to sort them
+			if !sortByAmount && count != 0 && len(entries) == count {
+				return errStopIteration
+			}
+			return nil
+		})
+	})
+	if err != nil && err != errStopIteration {
+		return nil, err
+	}
+
+	if sortByAmount {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Amount.Compare(entries[j].Amount) == nano.BalanceCompBigger
+		})
+
+		if count != 0 && len(entries) > count {
+			entries = entries[:count]
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"errors"
+)
+
+// ProcessResult describes the outcome of processing a block.
+type ProcessResult byte
+
+const (
+	// ProcessProgress means the block was added to the ledger.
+	ProcessProgress ProcessResult = iota
+	// ProcessOld means the block is already in the ledger.
+	ProcessOld
+	// ProcessGapPrevious means the previous block is unknown. The block was
+	// added to the unchecked list.
+	ProcessGapPrevious
+	// ProcessGapSource means the source block is unknown. The block was added
+	// to the unchecked list.
+	ProcessGapSource
+	// ProcessFork means the block competes with a block that is already in the
+	// ledger. It was recorded as a fork. See ResolveFork.
+	ProcessFork
+	// ProcessBadSignature means the block wasn't signed by the owner of the
+	// account.
+	ProcessBadSignature
+	// ProcessBadWork means the work value of the block doesn't meet the
+	// threshold.
+	ProcessBadWork
+	// ProcessNegativeSpend means the block sends more than the balance of the
+	// account.
+	ProcessNegativeSpend
+	// ProcessUnreceivable means the source block is known, but it's not
+	// pending for the account.
+	ProcessUnreceivable
+	// ProcessBalanceMismatch means the balance of the block doesn't match the
+	// amount that was received.
+	ProcessBalanceMismatch
+	// ProcessBlockPosition means the previous block belongs to another
+	// account, or the block can't be added to an account in its epoch.
+	ProcessBlockPosition
+	// ProcessOpenedBurnAccount means the block tries to open the burn account.
+	ProcessOpenedBurnAccount
+	// ProcessRepresentativeMismatch means the epoch block changes the
+	// representative of the account.
+	ProcessRepresentativeMismatch
+	// ProcessGapEpochOpenPending means the epoch block opens an account that
+	// has nothing to receive.
+	ProcessGapEpochOpenPending
+)
+
+var (
+	ErrBadSignature           = errors.New("bad block signature")
+	ErrNegativeSpend          = errors.New("negative spend")
+	ErrUnreceivable           = errors.New("source block is not pending for this account")
+	ErrBalanceMismatch        = errors.New("balance doesn't match the amount received")
+	ErrBlockPosition          = errors.New("previous block belongs to another account")
+	ErrOpenedBurnAccount      = errors.New("the burn account can't be opened")
+	ErrEpochPosition          = errors.New("block can't be added to an account in this epoch")
+	ErrRepresentativeMismatch = errors.New("epoch block changes the representative")
+	ErrGapEpochOpenPending    = errors.New("epoch block opens an account without pending transactions")
+
+	processResultNames = map[ProcessResult]string{
+		ProcessProgress:               "progress",
+		ProcessOld:                    "old",
+		ProcessGapPrevious:            "gap_previous",
+		ProcessGapSource:              "gap_source",
+		ProcessFork:                   "fork",
+		ProcessBadSignature:           "bad_signature",
+		ProcessBadWork:                "bad_work",
+		ProcessNegativeSpend:          "negative_spend",
+		ProcessUnreceivable:           "unreceivable",
+		ProcessBalanceMismatch:        "balance_mismatch",
+		ProcessBlockPosition:          "block_position",
+		ProcessOpenedBurnAccount:      "opened_burn_account",
+		ProcessRepresentativeMismatch: "representative_mismatch",
+		ProcessGapEpochOpenPending:    "gap_epoch_open_pending",
+	}
+
+	// processResults maps the errors returned while adding a block to the
+	// corresponding result. Any other error is a failure of the store.
+	processResults = map[error]ProcessResult{
+		ErrBlockExists:            ProcessOld,
+		ErrMissingPrevious:        ProcessGapPrevious,
+		ErrMissingSource:          ProcessGapSource,
+		ErrFork:                   ProcessFork,
+		ErrBadSignature:           ProcessBadSignature,
+		ErrBadWork:                ProcessBadWork,
+		ErrNegativeSpend:          ProcessNegativeSpend,
+		ErrUnreceivable:           ProcessUnreceivable,
+		ErrBalanceMismatch:        ProcessBalanceMismatch,
+		ErrBlockPosition:          ProcessBlockPosition,
+		ErrOpenedBurnAccount:      ProcessOpenedBurnAccount,
+		ErrEpochPosition:          ProcessBlockPosition,
+		ErrRepresentativeMismatch: ProcessRepresentativeMismatch,
+		ErrGapEpochOpenPending:    ProcessGapEpochOpenPending,
+	}
+)
+
+// String implements the fmt.Stringer interface.
+func (r ProcessResult) String() string {
+	if name, ok := processResultNames[r]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// Accepted reports whether the block is in the ledger after processing it.
+func (r ProcessResult) Accepted() bool {
+	return r == ProcessProgress || r == ProcessOld
+}
+
+// processResult converts the error returned while adding a block to a
+// ProcessResult. If the error is not the result of a rejected block, it is
+// returned as is.
+func processResult(err error) (ProcessResult, error) {
+	if err == nil {
+		return ProcessProgress, nil
+	}
+
+	if res, ok := processResults[err]; ok {
+		return res, nil
+	}
+
+	return 0, err
+}
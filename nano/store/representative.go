@@ -0,0 +1,64 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/alexbakker/gonano/nano"
+)
+
+// Representative describes an account that other accounts delegated their
+// voting weight to.
+type Representative struct {
+	Address nano.Address
+	Weight  nano.Balance
+	// Delegators is the amount of accounts that chose this representative.
+	Delegators int
+}
+
+// Representatives returns all representatives that have voting weight, sorted
+// by weight with the largest first, along with the total voting weight of all
+// representatives. Counting the delegators requires visiting every account in
+// the ledger, so this is an expensive call on large ledgers.
+func (l *Ledger) Representatives() ([]*Representative, nano.Balance, error) {
+	var reps []*Representative
+	total := nano.ZeroBalance
+
+	err := l.db.View(func(txn StoreTxn) error {
+		delegators := map[nano.Address]int{}
+		err := txn.WalkAddresses(func(address nano.Address, info *AddressInfo) error {
+			rep, err := l.getRepresentative(txn, address)
+			if err != nil {
+				return err
+			}
+
+			delegators[rep]++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return txn.WalkRepresentations(func(address nano.Address, weight nano.Balance) error {
+			if weight.Equal(nano.ZeroBalance) {
+				return nil
+			}
+
+			reps = append(reps, &Representative{
+				Address:    address,
+				Weight:     weight,
+				Delegators: delegators[address],
+			})
+			total = total.Add(weight)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nano.ZeroBalance, err
+	}
+
+	sort.SliceStable(reps, func(i, j int) bool {
+		return reps[i].Weight.Compare(reps[j].Weight) == nano.BalanceCompBigger
+	})
+
+	return reps, total, nil
+}
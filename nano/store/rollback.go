@@ -0,0 +1,326 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+var (
+	ErrRollbackGenesis   = errors.New("the genesis block can't be rolled back")
+	ErrRollbackConfirmed = errors.New("confirmed blocks can't be rolled back")
+)
+
+// Rollback removes the block with the given hash and all of its successors on
+// the account chain from the ledger. If any of the removed blocks is a send
+// that was already received, the receiving block (and its successors) is
+// rolled back as well.
+func (l *Ledger) Rollback(hash block.Hash) error {
+	return l.db.Update(func(txn StoreTxn) error {
+		return l.rollback(txn, hash)
+	})
+}
+
+func (l *Ledger) rollback(txn StoreTxn, hash block.Hash) error {
+	if hash == l.opts.Genesis.Block.Hash() {
+		return ErrRollbackGenesis
+	}
+
+	found, err := txn.HasBlock(hash)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	address, err := l.blockAccount(txn, hash)
+	if err != nil {
+		return err
+	}
+
+	// roll back the head of the account until the given block is gone
+	for {
+		info, err := txn.GetAddress(address)
+		if err != nil {
+			return err
+		}
+
+		head := info.HeadBlock
+		if err := l.rollbackBlock(txn, address, info, head); err != nil {
+			return err
+		}
+
+		if head == hash {
+			return nil
+		}
+	}
+}
+
+// rollbackBlock undoes the effects of the head block of the given address.
+func (l *Ledger) rollbackBlock(txn StoreTxn, address nano.Address, info *AddressInfo, hash block.Hash) error {
+	confirmed, err := txn.HasConfirmation(hash)
+	if err != nil {
+		return err
+	}
+	if confirmed {
+		return ErrRollbackConfirmed
+	}
+
+	blk, err := txn.GetBlock(hash)
+	if err != nil {
+		return err
+	}
+
+	balance, err := l.blockBalance(txn, hash)
+	if err != nil {
+		return err
+	}
+
+	_, rep, err := l.blockRepresentative(txn, hash)
+	if err != nil {
+		return err
+	}
+
+	var prevBalance nano.Balance
+	prevHash := blk.Previous()
+	if !prevHash.IsZero() {
+		if prevBalance, err = l.blockBalance(txn, prevHash); err != nil {
+			return err
+		}
+	}
+
+	// figure out whether funds were sent or received by this block
+	var destination nano.Address
+	var source block.Hash
+	switch b := blk.(type) {
+	case *block.SendBlock:
+		destination = b.Destination
+	case *block.ReceiveBlock:
+		source = b.SourceHash
+	case *block.OpenBlock:
+		source = b.SourceHash
+	case *block.StateBlock:
+		switch b.Balance.Compare(prevBalance) {
+		case nano.BalanceCompSmaller:
+			destination = nano.Address(b.Link)
+		case nano.BalanceCompBigger:
+			source = b.Link
+		}
+	default:
+		return block.ErrBadBlockType
+	}
+
+	if !source.IsZero() {
+		// put the received funds back in the pending list
+		sourceSideband, err := txn.GetSideband(source)
+		if err != nil {
+			return err
+		}
+
+		pending := Pending{
+			Address: sourceSideband.Account,
+			Amount:  balance.Sub(prevBalance),
+			Epoch:   sourceSideband.Epoch,
+		}
+		if err := txn.AddPending(address, source, &pending); err != nil {
+			return err
+		}
+	} else if balance.Compare(prevBalance) == nano.BalanceCompSmaller {
+		// if the funds were already received, roll back the receiving block first
+		if _, err := txn.GetPending(destination, hash); err != nil {
+			if err != ErrNotFound {
+				return err
+			}
+
+			receiver, err := l.findReceiver(txn, destination, hash)
+			if err != nil {
+				return err
+			}
+
+			if err := l.rollback(txn, receiver); err != nil {
+				return err
+			}
+		}
+
+		if err := txn.DeletePending(destination, hash); err != nil {
+			return err
+		}
+	}
+
+	// restore representative voting weight
+	if err := txn.SubRepresentation(rep, balance); err != nil {
+		return err
+	}
+
+	if prevHash.IsZero() {
+		// this was the first block of the account, remove it altogether
+		if err := txn.DeleteAddress(address); err != nil {
+			return err
+		}
+	} else {
+		repBlock, prevRep, err := l.blockRepresentative(txn, prevHash)
+		if err != nil {
+			return err
+		}
+		if err := txn.AddRepresentation(prevRep, prevBalance); err != nil {
+			return err
+		}
+
+		prevSideband, err := txn.GetSideband(prevHash)
+		if err != nil {
+			return err
+		}
+
+		info.HeadBlock = prevHash
+		info.RepBlock = repBlock
+		info.Balance = prevBalance
+		info.BlockCount--
+		info.Epoch = prevSideband.Epoch
+		if err := txn.UpdateAddress(address, info); err != nil {
+			return err
+		}
+
+		prevSideband.Successor = block.Hash{}
+		if err := txn.UpdateSideband(prevHash, prevSideband); err != nil {
+			return err
+		}
+
+		frontier := block.Frontier{
+			Address: address,
+			Hash:    prevHash,
+		}
+		if err := txn.AddFrontier(&frontier); err != nil {
+			return err
+		}
+	}
+
+	if err := txn.DeleteFrontier(hash); err != nil {
+		return err
+	}
+
+	if err := txn.DeleteSideband(hash); err != nil {
+		return err
+	}
+
+	return txn.DeleteBlock(hash)
+}
+
+// findReceiver looks for the block on the chain of the given address that
+// received the given send block.
+func (l *Ledger) findReceiver(txn StoreTxn, address nano.Address, send block.Hash) (block.Hash, error) {
+	info, err := txn.GetAddress(address)
+	if err != nil {
+		return block.Hash{}, err
+	}
+
+	for hash := info.HeadBlock; !hash.IsZero(); {
+		blk, err := txn.GetBlock(hash)
+		if err != nil {
+			return block.Hash{}, err
+		}
+
+		var source block.Hash
+		switch b := blk.(type) {
+		case *block.ReceiveBlock:
+			source = b.SourceHash
+		case *block.OpenBlock:
+			source = b.SourceHash
+		case *block.StateBlock:
+			source = b.Link
+		}
+		if source == send {
+			return hash, nil
+		}
+
+		hash = blk.Previous()
+	}
+
+	return block.Hash{}, fmt.Errorf("receiver of %s not found", send)
+}
+
+// blockAccount returns the address of the account chain the block with the
+// given hash belongs to.
+func (l *Ledger) blockAccount(txn StoreTxn, hash block.Hash) (nano.Address, error) {
+	sideband, err := txn.GetSideband(hash)
+	if err != nil {
+		return nano.Address{}, err
+	}
+
+	return sideband.Account, nil
+}
+
+// blockBalance returns the balance of the account after the block with the
+// given hash was added to its chain.
+func (l *Ledger) blockBalance(txn StoreTxn, hash block.Hash) (nano.Balance, error) {
+	sideband, err := txn.GetSideband(hash)
+	if err != nil {
+		return nano.ZeroBalance, err
+	}
+
+	return sideband.Balance, nil
+}
+
+// blockAmount returns the amount that was sent or received by the block with
+// the given hash.
+func (l *Ledger) blockAmount(txn StoreTxn, hash block.Hash) (nano.Balance, error) {
+	if hash == l.opts.Genesis.Block.Hash() {
+		return l.opts.Genesis.Balance, nil
+	}
+
+	blk, err := txn.GetBlock(hash)
+	if err != nil {
+		return nano.ZeroBalance, err
+	}
+
+	switch b := blk.(type) {
+	case *block.OpenBlock:
+		return l.blockAmount(txn, b.SourceHash)
+	case *block.ReceiveBlock:
+		return l.blockAmount(txn, b.SourceHash)
+	case *block.ChangeBlock:
+		return nano.ZeroBalance, nil
+	}
+
+	balance, err := l.blockBalance(txn, hash)
+	if err != nil {
+		return nano.ZeroBalance, err
+	}
+
+	prevBalance := nano.ZeroBalance
+	if prevHash := blk.Previous(); !prevHash.IsZero() {
+		if prevBalance, err = l.blockBalance(txn, prevHash); err != nil {
+			return nano.ZeroBalance, err
+		}
+	}
+
+	if balance.Compare(prevBalance) == nano.BalanceCompSmaller {
+		return prevBalance.Sub(balance), nil
+	}
+	return balance.Sub(prevBalance), nil
+}
+
+// blockRepresentative returns the representative of the account after the
+// block with the given hash was added to its chain, along with the hash of the
+// block that set it.
+func (l *Ledger) blockRepresentative(txn StoreTxn, hash block.Hash) (block.Hash, nano.Address, error) {
+	for {
+		blk, err := txn.GetBlock(hash)
+		if err != nil {
+			return block.Hash{}, nano.Address{}, err
+		}
+
+		switch b := blk.(type) {
+		case *block.OpenBlock:
+			return hash, b.Representative, nil
+		case *block.ChangeBlock:
+			return hash, b.Representative, nil
+		case *block.StateBlock:
+			return hash, b.Representative, nil
+		}
+
+		hash = blk.Previous()
+	}
+}
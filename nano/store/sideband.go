@@ -0,0 +1,105 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/internal/util"
+)
+
+// Sideband holds information about a block that follows from its position in
+// the ledger rather than from the block itself.
+type Sideband struct {
+	// Successor is the hash of the next block on the account chain. It's zero
+	// for the head block.
+	Successor block.Hash
+	// Height is the position of the block on the account chain. The open
+	// block has height 1.
+	Height uint64
+	// Account is the address of the account chain the block belongs to.
+	Account nano.Address
+	// Balance is the balance of the account after this block.
+	Balance nano.Balance
+	// Timestamp is the time the block was added to the local ledger, in
+	// seconds since the Unix epoch. It's zero for blocks that were added
+	// before sidebands were introduced.
+	Timestamp int64
+	// Epoch is the epoch of the account after this block.
+	Epoch block.Epoch
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (s *Sideband) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	var err error
+	if _, err = buf.Write(s.Successor[:]); err != nil {
+		return nil, err
+	}
+
+	if err = binary.Write(buf, binary.BigEndian, s.Height); err != nil {
+		return nil, err
+	}
+
+	if _, err = buf.Write(s.Account[:]); err != nil {
+		return nil, err
+	}
+
+	if _, err = buf.Write(s.Balance.Bytes(binary.BigEndian)); err != nil {
+		return nil, err
+	}
+
+	if err = binary.Write(buf, binary.BigEndian, s.Timestamp); err != nil {
+		return nil, err
+	}
+
+	if err = buf.WriteByte(byte(s.Epoch)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (s *Sideband) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+
+	var err error
+	if _, err = reader.Read(s.Successor[:]); err != nil {
+		return err
+	}
+
+	if err = binary.Read(reader, binary.BigEndian, &s.Height); err != nil {
+		return err
+	}
+
+	if _, err = reader.Read(s.Account[:]); err != nil {
+		return err
+	}
+
+	balance := make([]byte, nano.BalanceSize)
+	if _, err = reader.Read(balance); err != nil {
+		return err
+	}
+	if err = s.Balance.UnmarshalBinary(balance); err != nil {
+		return err
+	}
+
+	if err = binary.Read(reader, binary.BigEndian, &s.Timestamp); err != nil {
+		return err
+	}
+
+	// the epoch is missing in databases with schema version 1 and older
+	s.Epoch = block.Epoch0
+	if reader.Len() > 0 {
+		epoch, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		s.Epoch = block.Epoch(epoch)
+	}
+
+	return util.AssertReaderEOF(reader)
+}
@@ -0,0 +1,356 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	snapshotMagic   = "GONANOSS"
+	snapshotVersion = 1
+	// the amount of blocks that are imported in a single transaction
+	snapshotBatchSize = 10000
+)
+
+var (
+	ErrBadSnapshot      = errors.New("not a gonano ledger snapshot")
+	ErrSnapshotChecksum = errors.New("snapshot checksum mismatch")
+)
+
+// ExportSnapshot writes all blocks in the ledger to w in topological order:
+// the genesis block comes first and every block comes after its previous and
+// source blocks. It returns the amount of blocks that were written.
+//
+// A snapshot starts with a magic value and a version number, followed by the
+// blocks. Every block is encoded as its type and its binary representation, as
+// in the bulk pull protocol. The blocks are terminated by a not_a_block type,
+// followed by the amount of blocks and a BLAKE2b-256 checksum of everything
+// before it.
+func (l *Ledger) ExportSnapshot(w io.Writer) (uint64, error) {
+	digest, err := blake2b.New256(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	writer := bufio.NewWriter(io.MultiWriter(w, digest))
+	if _, err := writer.WriteString(snapshotMagic); err != nil {
+		return 0, err
+	}
+	if err := writer.WriteByte(snapshotVersion); err != nil {
+		return 0, err
+	}
+
+	var count uint64
+	err = l.db.View(func(txn StoreTxn) error {
+		var heads []block.Hash
+		err := txn.WalkAddresses(func(address nano.Address, info *AddressInfo) error {
+			heads = append(heads, info.HeadBlock)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		written := map[block.Hash]struct{}{}
+		for _, head := range heads {
+			// depth-first search for blocks with no unwritten dependencies
+			stack := []block.Hash{head}
+			for len(stack) > 0 {
+				hash := stack[len(stack)-1]
+				if _, ok := written[hash]; ok {
+					stack = stack[:len(stack)-1]
+					continue
+				}
+
+				blk, err := txn.GetBlock(hash)
+				if err != nil {
+					return err
+				}
+
+				deps, err := l.snapshotDependencies(txn, blk)
+				if err != nil {
+					return err
+				}
+
+				var pending bool
+				for _, dep := range deps {
+					if _, ok := written[dep]; !ok {
+						stack = append(stack, dep)
+						pending = true
+					}
+				}
+				if pending {
+					continue
+				}
+
+				blockBytes, err := blk.MarshalBinary()
+				if err != nil {
+					return err
+				}
+				if err := writer.WriteByte(blk.ID()); err != nil {
+					return err
+				}
+				if _, err := writer.Write(blockBytes); err != nil {
+					return err
+				}
+
+				written[hash] = struct{}{}
+				stack = stack[:len(stack)-1]
+				count++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writer.WriteByte(block.IDNotABlock); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(writer, binary.LittleEndian, count); err != nil {
+		return 0, err
+	}
+
+	// the checksum itself is not part of the hash
+	if err := writer.Flush(); err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(digest.Sum(nil)); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// snapshotDependencies returns the hashes of the blocks that need to be in the
+// ledger before the given block can be added.
+func (l *Ledger) snapshotDependencies(txn StoreTxn, blk block.Block) ([]block.Hash, error) {
+	var deps []block.Hash
+	if prevHash := blk.Previous(); !prevHash.IsZero() {
+		deps = append(deps, prevHash)
+	}
+
+	var source block.Hash
+	switch b := blk.(type) {
+	case *block.OpenBlock:
+		source = b.SourceHash
+	case *block.ReceiveBlock:
+		source = b.SourceHash
+	case *block.StateBlock:
+		// epoch open blocks require the account to have pending funds
+		if _, ok := l.opts.Genesis.EpochOf(b.Link); ok && b.IsOpen() {
+			send, err := l.epochOpenSend(txn, b)
+			if err != nil {
+				return nil, err
+			}
+			if !send.IsZero() {
+				deps = append(deps, send)
+			}
+			return deps, nil
+		}
+
+		// the link is only a block hash for receives
+		source = b.Link
+	}
+
+	if !source.IsZero() {
+		found, err := txn.HasBlock(source)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			deps = append(deps, source)
+		}
+	}
+
+	return deps, nil
+}
+
+// epochOpenSend returns the hash of a send to the account of the given epoch
+// open block. The source of the first block that received funds on the account
+// chain is preferred, as that's usually the send the account was opened for.
+// If the account never received anything, one of its pending sends is
+// returned. A zero hash is returned if there's no send to the account at all.
+func (l *Ledger) epochOpenSend(txn StoreTxn, open *block.StateBlock) (block.Hash, error) {
+	sideband, err := txn.GetSideband(open.Hash())
+	if err != nil {
+		return block.Hash{}, err
+	}
+
+	balance := sideband.Balance
+	for hash := sideband.Successor; !hash.IsZero(); {
+		blk, err := txn.GetBlock(hash)
+		if err != nil {
+			return block.Hash{}, err
+		}
+		if sideband, err = txn.GetSideband(hash); err != nil {
+			return block.Hash{}, err
+		}
+
+		// only state blocks can follow an epoch open block
+		if b, ok := blk.(*block.StateBlock); ok && b.Balance.Compare(balance) == nano.BalanceCompBigger {
+			return b.Link, nil
+		}
+
+		balance = sideband.Balance
+		hash = sideband.Successor
+	}
+
+	var send block.Hash
+	err = txn.WalkPending(open.Address, func(hash block.Hash, pending *Pending) error {
+		send = hash
+		return errStopIteration
+	})
+	if err != nil && err != errStopIteration {
+		return block.Hash{}, err
+	}
+
+	return send, nil
+}
+
+// ImportSnapshot adds the blocks in the snapshot read from r to the ledger. The
+// blocks are validated like any other block, so an error is returned if one of
+// them is rejected. It returns the amount of blocks that were imported.
+//
+// The snapshot is read twice: the checksum and the amount of blocks are
+// verified before any blocks are added to the ledger.
+func (l *Ledger) ImportSnapshot(r io.ReadSeeker) (uint64, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := readSnapshot(r, func(blk block.Block) error { return nil }); err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var count uint64
+	var batch []block.Block
+	_, err = readSnapshot(r, func(blk block.Block) error {
+		batch = append(batch, blk)
+		if len(batch) == snapshotBatchSize {
+			if err := l.importBlocks(batch); err != nil {
+				return err
+			}
+
+			count += uint64(len(batch))
+			batch = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if err := l.importBlocks(batch); err != nil {
+		return count, err
+	}
+	count += uint64(len(batch))
+
+	return count, nil
+}
+
+// readSnapshot calls visit for every block in the snapshot read from r. It
+// returns the amount of blocks that were read. ErrSnapshotChecksum is returned
+// if the checksum doesn't match, after all blocks were visited.
+func readSnapshot(r io.Reader, visit func(blk block.Block) error) (uint64, error) {
+	digest, err := blake2b.New256(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// only the bytes before the checksum are hashed
+	bufReader := bufio.NewReader(r)
+	reader := io.TeeReader(bufReader, digest)
+
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, ErrBadSnapshot
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return 0, ErrBadSnapshot
+	}
+	if version := header[len(snapshotMagic)]; version != snapshotVersion {
+		return 0, fmt.Errorf("unsupported snapshot version: %d", version)
+	}
+
+	var count uint64
+	for {
+		var id [1]byte
+		if _, err := io.ReadFull(reader, id[:]); err != nil {
+			return count, err
+		}
+		if id[0] == block.IDNotABlock {
+			break
+		}
+
+		blk, err := block.New(id[0])
+		if err != nil {
+			return count, err
+		}
+
+		blockBytes := make([]byte, blk.Size())
+		if _, err := io.ReadFull(reader, blockBytes); err != nil {
+			return count, err
+		}
+		if err := blk.UnmarshalBinary(blockBytes); err != nil {
+			return count, err
+		}
+
+		if err := visit(blk); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	var expectedCount uint64
+	if err := binary.Read(reader, binary.LittleEndian, &expectedCount); err != nil {
+		return count, err
+	}
+
+	checksum := make([]byte, blake2b.Size256)
+	if _, err := io.ReadFull(bufReader, checksum); err != nil {
+		return count, err
+	}
+	if !bytes.Equal(checksum, digest.Sum(nil)) {
+		return count, ErrSnapshotChecksum
+	}
+
+	if expectedCount != count {
+		return count, ErrBadSnapshot
+	}
+
+	return count, nil
+}
+
+// importBlocks adds the given blocks to the ledger in a single transaction.
+func (l *Ledger) importBlocks(blocks []block.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	results, err := l.AddBlocks(blocks)
+	if err != nil {
+		return err
+	}
+
+	for i, res := range results {
+		if !res.Accepted() {
+			return fmt.Errorf("block %s was rejected: %s", blocks[i].Hash(), res)
+		}
+	}
+
+	return nil
+}
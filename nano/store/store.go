@@ -10,6 +10,7 @@ import (
 var (
 	ErrBlockExists = errors.New("block already exists")
 	ErrStoreEmpty  = errors.New("the store is empty")
+	ErrNotFound    = errors.New("item not found in the store")
 )
 
 type UncheckedKind byte
@@ -19,6 +20,26 @@ const (
 	UncheckedKindSource
 )
 
+// BlockWalkFunc is the type of the function called for each block visited by
+// WalkBlocks.
+type BlockWalkFunc func(block block.Block) error
+
+// AddressWalkFunc is the type of the function called for each address visited
+// by WalkAddresses.
+type AddressWalkFunc func(address nano.Address, info *AddressInfo) error
+
+// PendingWalkFunc is the type of the function called for each pending
+// transaction visited by WalkPending.
+type PendingWalkFunc func(hash block.Hash, pending *Pending) error
+
+// AllPendingWalkFunc is the type of the function called for each pending
+// transaction visited by WalkAllPending.
+type AllPendingWalkFunc func(destination nano.Address, hash block.Hash, pending *Pending) error
+
+// RepresentationWalkFunc is the type of the function called for each
+// representative visited by WalkRepresentations.
+type RepresentationWalkFunc func(address nano.Address, amount nano.Balance) error
+
 // UncheckedBlockWalkFunc is the type of the function called for each unchecked
 // block visited by WalkUncheckedBlocks.
 type UncheckedBlockWalkFunc func(block block.Block, kind UncheckedKind) error
@@ -34,12 +55,22 @@ type StoreTxn interface {
 	Empty() (bool, error)
 	Flush() error
 
+	GetVersion() (uint64, error)
+	SetVersion(version uint64) error
+
 	AddBlock(blk block.Block) error
 	GetBlock(hash block.Hash) (block.Block, error)
 	DeleteBlock(hash block.Hash) error
 	HasBlock(hash block.Hash) (bool, error)
+	WalkBlocks(visit BlockWalkFunc) error
+	SeekBlocks(start block.Hash, visit BlockWalkFunc) error
 	CountBlocks() (uint64, error)
 
+	AddSideband(hash block.Hash, sideband *Sideband) error
+	GetSideband(hash block.Hash) (*Sideband, error)
+	UpdateSideband(hash block.Hash, sideband *Sideband) error
+	DeleteSideband(hash block.Hash) error
+
 	AddUncheckedBlock(parentHash block.Hash, blk block.Block, kind UncheckedKind) error
 	GetUncheckedBlock(parentHash block.Hash, kind UncheckedKind) (block.Block, error)
 	DeleteUncheckedBlock(parentHash block.Hash, kind UncheckedKind) error
@@ -52,6 +83,8 @@ type StoreTxn interface {
 	UpdateAddress(address nano.Address, info *AddressInfo) error
 	DeleteAddress(address nano.Address) error
 	HasAddress(address nano.Address) (bool, error)
+	WalkAddresses(visit AddressWalkFunc) error
+	SeekAddresses(start nano.Address, visit AddressWalkFunc) error
 
 	AddFrontier(frontier *block.Frontier) error
 	GetFrontier(hash block.Hash) (*block.Frontier, error)
@@ -59,11 +92,24 @@ type StoreTxn interface {
 	DeleteFrontier(hash block.Hash) error
 	CountFrontiers() (uint64, error)
 
+	AddConfirmation(hash block.Hash) error
+	HasConfirmation(hash block.Hash) (bool, error)
+
+	AddFork(root block.Hash, blk block.Block) error
+	GetForks(root block.Hash) ([]block.Block, error)
+	DeleteForks(root block.Hash) error
+
 	AddPending(destination nano.Address, hash block.Hash, pending *Pending) error
 	GetPending(destination nano.Address, hash block.Hash) (*Pending, error)
 	DeletePending(destination nano.Address, hash block.Hash) error
+	WalkPending(destination nano.Address, visit PendingWalkFunc) error
+	WalkAllPending(visit AllPendingWalkFunc) error
+
+	GetVoteSequence(address nano.Address) (uint64, error)
+	SetVoteSequence(address nano.Address, sequence uint64) error
 
 	AddRepresentation(address nano.Address, amount nano.Balance) error
 	SubRepresentation(address nano.Address, amount nano.Balance) error
 	GetRepresentation(address nano.Address) (nano.Balance, error)
+	WalkRepresentations(visit RepresentationWalkFunc) error
 }
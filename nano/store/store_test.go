@@ -0,0 +1,641 @@
+package store
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+type testStore struct {
+	name string
+	open func(t testing.TB) (Store, func())
+}
+
+var (
+	errTestRollback = errors.New("rollback")
+
+	testStores = []testStore{
+		{"badger", openTestBadgerStore},
+		{"memory", openTestMemoryStore},
+		{"file", openTestFileStore},
+	}
+)
+
+func openTestBadgerStore(t testing.TB) (Store, func()) {
+	dir, err := ioutil.TempDir("", "gonano_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewBadgerStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, func() {
+		if err := store.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func openTestFileStore(t testing.TB) (Store, func()) {
+	dir, err := ioutil.TempDir("", "gonano_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, func() {
+		if err := store.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func openTestMemoryStore(t testing.TB) (Store, func()) {
+	store := NewMemoryStore()
+	return store, func() {
+		if err := store.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// runStoreTest runs the given test against every store implementation.
+func runStoreTest(t *testing.T, test func(t *testing.T, store Store)) {
+	for _, s := range testStores {
+		s := s
+		t.Run(s.name, func(t *testing.T) {
+			store, cleanup := s.open(t)
+			defer cleanup()
+
+			test(t, store)
+		})
+	}
+}
+
+func mustUpdate(t *testing.T, store Store, fn func(txn StoreTxn) error) {
+	if err := store.Update(fn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustView(t *testing.T, store Store, fn func(txn StoreTxn) error) {
+	if err := store.View(fn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreBlocks(t *testing.T) {
+	runStoreTest(t, func(t *testing.T, store Store) {
+		blk := generateBlock(t)
+		hash := blk.Hash()
+
+		mustView(t, store, func(txn StoreTxn) error {
+			if empty, err := txn.Empty(); err != nil || !empty {
+				t.Fatalf("store not empty: %v, %v", empty, err)
+			}
+			if _, err := txn.GetBlock(hash); err != ErrNotFound {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return nil
+		})
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			if err := txn.AddBlock(blk); err != nil {
+				return err
+			}
+			if err := txn.AddBlock(blk); err != ErrBlockExists {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return txn.AddBlock(generateBlock(t))
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			if empty, err := txn.Empty(); err != nil || empty {
+				t.Fatalf("store empty: %v, %v", empty, err)
+			}
+			if ok, err := txn.HasBlock(hash); err != nil || !ok {
+				t.Fatalf("block not found: %v, %v", ok, err)
+			}
+
+			res, err := txn.GetBlock(hash)
+			if err != nil {
+				return err
+			}
+			if res.ID() != blk.ID() || res.Hash() != hash {
+				t.Fatalf("unexpected block: %s", res.Hash())
+			}
+
+			if count, err := txn.CountBlocks(); err != nil || count != 2 {
+				t.Fatalf("unexpected block count: %d, %v", count, err)
+			}
+
+			var last []byte
+			var n int
+			err = txn.WalkBlocks(func(blk block.Block) error {
+				hash := blk.Hash()
+				if last != nil && string(last) >= string(hash[:]) {
+					t.Fatalf("blocks not ordered by hash")
+				}
+				last = hash[:]
+				n++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if n != 2 {
+				t.Fatalf("unexpected amount of blocks walked: %d", n)
+			}
+			return nil
+		})
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			return txn.DeleteBlock(hash)
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			if ok, err := txn.HasBlock(hash); err != nil || ok {
+				t.Fatalf("block not deleted: %v, %v", ok, err)
+			}
+			return nil
+		})
+	})
+}
+
+func TestStoreSeekBlocks(t *testing.T) {
+	runStoreTest(t, func(t *testing.T, store Store) {
+		var hashes []string
+		addBlocks := func(txn StoreTxn, n int) error {
+			for i := 0; i < n; i++ {
+				blk := generateBlock(t)
+				if err := txn.AddBlock(blk); err != nil {
+					return err
+				}
+
+				hash := blk.Hash()
+				hashes = append(hashes, string(hash[:]))
+			}
+
+			sort.Strings(hashes)
+			return nil
+		}
+
+		assertSeek := func(txn StoreTxn, i int) {
+			var start block.Hash
+			copy(start[:], hashes[i])
+
+			var res []string
+			err := txn.SeekBlocks(start, func(blk block.Block) error {
+				hash := blk.Hash()
+				res = append(res, string(hash[:]))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res, hashes[i:]) {
+				t.Fatalf("unexpected blocks after seeking to block %d: %d", i, len(res))
+			}
+		}
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			return addBlocks(txn, 10)
+		})
+
+		// blocks that were added in the same transaction are visited as well
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			if err := addBlocks(txn, 10); err != nil {
+				return err
+			}
+
+			for i := range hashes {
+				assertSeek(txn, i)
+			}
+			return nil
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			for i := range hashes {
+				assertSeek(txn, i)
+			}
+			return nil
+		})
+	})
+}
+
+func TestStoreUncheckedBlocks(t *testing.T) {
+	runStoreTest(t, func(t *testing.T, store Store) {
+		blk := generateBlock(t)
+		parent := randomHash(t)
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			if err := txn.AddUncheckedBlock(parent, blk, UncheckedKindPrevious); err != nil {
+				return err
+			}
+			return txn.AddUncheckedBlock(parent, blk, UncheckedKindSource)
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			if count, err := txn.CountUncheckedBlocks(); err != nil || count != 2 {
+				t.Fatalf("unexpected unchecked block count: %d, %v", count, err)
+			}
+			if count, err := txn.CountBlocks(); err != nil || count != 0 {
+				t.Fatalf("unexpected block count: %d, %v", count, err)
+			}
+
+			res, err := txn.GetUncheckedBlock(parent, UncheckedKindSource)
+			if err != nil {
+				return err
+			}
+			if res.Hash() != blk.Hash() {
+				t.Fatalf("unexpected block: %s", res.Hash())
+			}
+
+			var kinds []UncheckedKind
+			err = txn.WalkUncheckedBlocks(func(blk block.Block, kind UncheckedKind) error {
+				kinds = append(kinds, kind)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(kinds) != 2 || kinds[0] != UncheckedKindPrevious || kinds[1] != UncheckedKindSource {
+				t.Fatalf("unexpected unchecked blocks: %v", kinds)
+			}
+			return nil
+		})
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			return txn.DeleteUncheckedBlock(parent, UncheckedKindPrevious)
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			if ok, err := txn.HasUncheckedBlock(parent, UncheckedKindPrevious); err != nil || ok {
+				t.Fatalf("unchecked block not deleted: %v, %v", ok, err)
+			}
+			if ok, err := txn.HasUncheckedBlock(parent, UncheckedKindSource); err != nil || !ok {
+				t.Fatalf("unchecked block not found: %v, %v", ok, err)
+			}
+			return nil
+		})
+	})
+}
+
+func TestStoreAddresses(t *testing.T) {
+	runStoreTest(t, func(t *testing.T, store Store) {
+		address := randomAddress(t)
+		info := AddressInfo{
+			HeadBlock:  randomHash(t),
+			RepBlock:   randomHash(t),
+			OpenBlock:  randomHash(t),
+			Balance:    nano.ParseBalanceInts(0, 1337),
+			BlockCount: 3,
+		}
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			if err := txn.AddAddress(address, &info); err != nil {
+				return err
+			}
+			if err := txn.AddAddress(address, &info); err == nil {
+				t.Fatal("address was overwritten")
+			}
+
+			info.BlockCount++
+			return txn.UpdateAddress(address, &info)
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			res, err := txn.GetAddress(address)
+			if err != nil {
+				return err
+			}
+			if *res != info {
+				t.Fatalf("unexpected address info: %+v", res)
+			}
+
+			var n int
+			err = txn.WalkAddresses(func(a nano.Address, info *AddressInfo) error {
+				if a != address {
+					t.Fatalf("unexpected address: %s", a)
+				}
+				n++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if n != 1 {
+				t.Fatalf("unexpected amount of addresses walked: %d", n)
+			}
+			return nil
+		})
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			return txn.DeleteAddress(address)
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			if ok, err := txn.HasAddress(address); err != nil || ok {
+				t.Fatalf("address not deleted: %v, %v", ok, err)
+			}
+			return nil
+		})
+	})
+}
+
+func TestStoreFrontiers(t *testing.T) {
+	runStoreTest(t, func(t *testing.T, store Store) {
+		frontier := block.Frontier{Address: randomAddress(t), Hash: randomHash(t)}
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			if err := txn.AddFrontier(&frontier); err != nil {
+				return err
+			}
+			if err := txn.AddFrontier(&frontier); err == nil {
+				t.Fatal("frontier was overwritten")
+			}
+			return nil
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			res, err := txn.GetFrontier(frontier.Hash)
+			if err != nil {
+				return err
+			}
+			if *res != frontier {
+				t.Fatalf("unexpected frontier: %+v", res)
+			}
+
+			frontiers, err := txn.GetFrontiers()
+			if err != nil {
+				return err
+			}
+			if len(frontiers) != 1 || *frontiers[0] != frontier {
+				t.Fatalf("unexpected frontiers: %v", frontiers)
+			}
+			return nil
+		})
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			return txn.DeleteFrontier(frontier.Hash)
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			if count, err := txn.CountFrontiers(); err != nil || count != 0 {
+				t.Fatalf("unexpected frontier count: %d, %v", count, err)
+			}
+			return nil
+		})
+	})
+}
+
+func TestStorePending(t *testing.T) {
+	runStoreTest(t, func(t *testing.T, store Store) {
+		destination := randomAddress(t)
+		other := randomAddress(t)
+		pending := Pending{Address: randomAddress(t), Amount: nano.ParseBalanceInts(0, 42)}
+
+		hashes := []block.Hash{randomHash(t), randomHash(t), randomHash(t)}
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			for _, hash := range hashes {
+				if err := txn.AddPending(destination, hash, &pending); err != nil {
+					return err
+				}
+			}
+			if err := txn.AddPending(destination, hashes[0], &pending); err == nil {
+				t.Fatal("pending transaction was overwritten")
+			}
+			return txn.AddPending(other, randomHash(t), &pending)
+		})
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			return txn.DeletePending(destination, hashes[1])
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			res, err := txn.GetPending(destination, hashes[0])
+			if err != nil {
+				return err
+			}
+			if *res != pending {
+				t.Fatalf("unexpected pending transaction: %+v", res)
+			}
+
+			if _, err := txn.GetPending(destination, hashes[1]); err != ErrNotFound {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var walked []block.Hash
+			err = txn.WalkPending(destination, func(hash block.Hash, pending *Pending) error {
+				walked = append(walked, hash)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if len(walked) != 2 || string(walked[0][:]) >= string(walked[1][:]) {
+				t.Fatalf("unexpected pending transactions: %v", walked)
+			}
+			return nil
+		})
+	})
+}
+
+func TestStoreForks(t *testing.T) {
+	runStoreTest(t, func(t *testing.T, store Store) {
+		root := randomHash(t)
+		blocks := []block.Block{generateBlock(t), generateBlock(t)}
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			for _, blk := range blocks {
+				if err := txn.AddFork(root, blk); err != nil {
+					return err
+				}
+			}
+			if err := txn.AddFork(root, blocks[0]); err != ErrBlockExists {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return txn.AddFork(randomHash(t), blocks[0])
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			forks, err := txn.GetForks(root)
+			if err != nil {
+				return err
+			}
+			if len(forks) != len(blocks) {
+				t.Fatalf("unexpected amount of forks: %d", len(forks))
+			}
+			return nil
+		})
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			return txn.DeleteForks(root)
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			if forks, err := txn.GetForks(root); err != nil || len(forks) != 0 {
+				t.Fatalf("forks not deleted: %v, %v", forks, err)
+			}
+			return nil
+		})
+	})
+}
+
+func TestStoreMisc(t *testing.T) {
+	runStoreTest(t, func(t *testing.T, store Store) {
+		hash := randomHash(t)
+		address := randomAddress(t)
+		sideband := Sideband{Height: 2, Account: address, Timestamp: 1337}
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			if err := txn.AddConfirmation(hash); err != nil {
+				return err
+			}
+			if err := txn.SetVoteSequence(address, 7); err != nil {
+				return err
+			}
+			if err := txn.AddRepresentation(address, nano.ParseBalanceInts(0, 10)); err != nil {
+				return err
+			}
+			if err := txn.SubRepresentation(address, nano.ParseBalanceInts(0, 3)); err != nil {
+				return err
+			}
+			if err := txn.AddSideband(hash, &sideband); err != nil {
+				return err
+			}
+			if err := txn.AddSideband(hash, &sideband); err == nil {
+				t.Fatal("sideband was overwritten")
+			}
+
+			sideband.Successor = randomHash(t)
+			return txn.UpdateSideband(hash, &sideband)
+		})
+
+		mustView(t, store, func(txn StoreTxn) error {
+			if ok, err := txn.HasConfirmation(hash); err != nil || !ok {
+				t.Fatalf("confirmation not found: %v, %v", ok, err)
+			}
+			if seq, err := txn.GetVoteSequence(address); err != nil || seq != 7 {
+				t.Fatalf("unexpected vote sequence: %d, %v", seq, err)
+			}
+
+			weight, err := txn.GetRepresentation(address)
+			if err != nil {
+				return err
+			}
+			if !weight.Equal(nano.ParseBalanceInts(0, 7)) {
+				t.Fatalf("unexpected weight: %s", weight)
+			}
+
+			var n int
+			err = txn.WalkRepresentations(func(a nano.Address, amount nano.Balance) error {
+				n++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if n != 1 {
+				t.Fatalf("unexpected amount of representatives walked: %d", n)
+			}
+
+			res, err := txn.GetSideband(hash)
+			if err != nil {
+				return err
+			}
+			if *res != sideband {
+				t.Fatalf("unexpected sideband: %+v", res)
+			}
+			return nil
+		})
+	})
+}
+
+func TestStoreRollback(t *testing.T) {
+	runStoreTest(t, func(t *testing.T, store Store) {
+		blk := generateBlock(t)
+		other := generateBlock(t)
+
+		mustUpdate(t, store, func(txn StoreTxn) error {
+			return txn.AddBlock(blk)
+		})
+
+		err := store.Update(func(txn StoreTxn) error {
+			if err := txn.DeleteBlock(blk.Hash()); err != nil {
+				return err
+			}
+			if err := txn.AddBlock(other); err != nil {
+				return err
+			}
+
+			// changes are visible within the transaction
+			if ok, err := txn.HasBlock(blk.Hash()); err != nil || ok {
+				t.Fatalf("block not deleted: %v, %v", ok, err)
+			}
+			if count, err := txn.CountBlocks(); err != nil || count != 1 {
+				t.Fatalf("unexpected block count: %d, %v", count, err)
+			}
+			return errTestRollback
+		})
+		if err != errTestRollback {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		mustView(t, store, func(txn StoreTxn) error {
+			if ok, err := txn.HasBlock(blk.Hash()); err != nil || !ok {
+				t.Fatalf("delete was not rolled back: %v, %v", ok, err)
+			}
+			if ok, err := txn.HasBlock(other.Hash()); err != nil || ok {
+				t.Fatalf("add was not rolled back: %v, %v", ok, err)
+			}
+			return nil
+		})
+	})
+}
+
+func TestAddressInfoVersion0(t *testing.T) {
+	info := AddressInfo{HeadBlock: randomHash(t), Balance: nano.ParseBalanceInts(0, 1), BlockCount: 2, Epoch: block.Epoch1}
+	infoBytes, err := info.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// version 1 didn't have the epoch
+	var res AddressInfo
+	if err := res.UnmarshalBinary(infoBytes[:len(infoBytes)-1]); err != nil {
+		t.Fatal(err)
+	}
+	info.Epoch = block.Epoch0
+	if res != info {
+		t.Fatalf("unexpected address info: %+v", res)
+	}
+
+	// version 0 didn't have the block count either
+	if err := res.UnmarshalBinary(infoBytes[:len(infoBytes)-9]); err != nil {
+		t.Fatal(err)
+	}
+	info.BlockCount = 0
+	if res != info {
+		t.Fatalf("unexpected address info: %+v", res)
+	}
+}
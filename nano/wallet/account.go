@@ -10,6 +10,7 @@ import (
 type Account struct {
 	pubKey  ed25519.PublicKey
 	privKey ed25519.PrivateKey
+	work    *WorkOptions
 }
 
 // NewAccount creates a new account with the given private key.
@@ -32,3 +33,8 @@ func (a *Account) Sign(hash block.Hash) block.Signature {
 	copy(sig[:], ed25519.Sign(a.privKey, hash[:]))
 	return sig
 }
+
+// wipe overwrites the private key of the account with zeroes.
+func (a *Account) wipe() {
+	wipeBytes(a.privKey)
+}
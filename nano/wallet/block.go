@@ -0,0 +1,155 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/store"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrAccountNotOpen      = errors.New("the account is not opened yet")
+	ErrZeroAmount          = errors.New("the amount must be greater than zero")
+)
+
+// AccountState is the state of an account chain that new blocks build on.
+type AccountState struct {
+	// Head is the hash of the head block of the account. It's zero if the
+	// account isn't opened yet.
+	Head           block.Hash
+	Representative nano.Address
+	Balance        nano.Balance
+	Epoch          block.Epoch
+}
+
+// WorkOptions describes how the block constructors of an Account attach work
+// to blocks.
+type WorkOptions struct {
+	Provider   block.WorkProvider
+	Thresholds block.WorkThresholds
+}
+
+// NewAccountState returns the state of an account with the given address info
+// and current representative.
+func NewAccountState(info *store.AddressInfo, rep nano.Address) *AccountState {
+	return &AccountState{
+		Head:           info.HeadBlock,
+		Representative: rep,
+		Balance:        info.Balance,
+		Epoch:          info.Epoch,
+	}
+}
+
+// Update applies the given block, which was built on this state, to the state.
+// The epoch is left as is: receiving funds from an upgraded account upgrades
+// the account to the epoch of the sender, which can't be derived from the
+// block. Callers that received funds should fetch the state of the account
+// again before building the next block.
+func (s *AccountState) Update(blk *block.StateBlock) {
+	s.Head = blk.Hash()
+	s.Representative = blk.Representative
+	s.Balance = blk.Balance
+}
+
+// SetWork sets the options that are used to attach work to the blocks that
+// are built for this account. If opts is nil, blocks are built without work.
+func (a *Account) SetWork(opts *WorkOptions) {
+	a.work = opts
+}
+
+// Send builds a block that sends the given amount to the given destination.
+func (a *Account) Send(ctx context.Context, state *AccountState, destination nano.Address, amount nano.Balance) (*block.StateBlock, error) {
+	if state.Head.IsZero() {
+		return nil, ErrAccountNotOpen
+	}
+	if amount.Equal(nano.ZeroBalance) {
+		return nil, ErrZeroAmount
+	}
+	if amount.Compare(state.Balance) == nano.BalanceCompBigger {
+		return nil, ErrInsufficientBalance
+	}
+
+	blk := a.stateBlock(state)
+	blk.Balance = state.Balance.Sub(amount)
+	blk.Link = block.Hash(destination)
+	return a.finish(ctx, blk, state.Epoch, block.SubtypeSend)
+}
+
+// Receive builds a block that receives the given amount from the given send
+// block. See Open for accounts that aren't opened yet.
+func (a *Account) Receive(ctx context.Context, state *AccountState, source block.Hash, amount nano.Balance) (*block.StateBlock, error) {
+	if state.Head.IsZero() {
+		return nil, ErrAccountNotOpen
+	}
+	if amount.Equal(nano.ZeroBalance) {
+		return nil, ErrZeroAmount
+	}
+
+	blk := a.stateBlock(state)
+	blk.Balance = state.Balance.Add(amount)
+	blk.Link = source
+	return a.finish(ctx, blk, state.Epoch, block.SubtypeReceive)
+}
+
+// Open builds the first block of the account, which receives the given amount
+// from the given send block and sets the representative of the account.
+func (a *Account) Open(ctx context.Context, source block.Hash, amount nano.Balance, rep nano.Address) (*block.StateBlock, error) {
+	if amount.Equal(nano.ZeroBalance) {
+		return nil, ErrZeroAmount
+	}
+
+	blk := &block.StateBlock{
+		Address:        a.Address(),
+		Representative: rep,
+		Balance:        amount,
+		Link:           source,
+	}
+	return a.finish(ctx, blk, block.Epoch0, block.SubtypeOpen)
+}
+
+// ChangeRep builds a block that changes the representative of the account.
+func (a *Account) ChangeRep(ctx context.Context, state *AccountState, rep nano.Address) (*block.StateBlock, error) {
+	if state.Head.IsZero() {
+		return nil, ErrAccountNotOpen
+	}
+
+	blk := a.stateBlock(state)
+	blk.Representative = rep
+	return a.finish(ctx, blk, state.Epoch, block.SubtypeChange)
+}
+
+// stateBlock returns a block that follows the head block of the given state
+// without changing anything.
+func (a *Account) stateBlock(state *AccountState) *block.StateBlock {
+	return &block.StateBlock{
+		Address:        a.Address(),
+		PreviousHash:   state.Head,
+		Representative: state.Representative,
+		Balance:        state.Balance,
+	}
+}
+
+// finish signs the given block and attaches work to it if a work provider was
+// set.
+func (a *Account) finish(ctx context.Context, blk *block.StateBlock, epoch block.Epoch, subtype block.Subtype) (*block.StateBlock, error) {
+	blk.Signature = a.Sign(blk.Hash())
+
+	if a.work != nil && a.work.Provider != nil {
+		// the work of open blocks is based on the account
+		root := blk.PreviousHash
+		if blk.IsOpen() {
+			root = block.Hash(blk.Address)
+		}
+
+		work, err := a.work.Provider.GenerateWork(ctx, root, a.work.Thresholds.Threshold(epoch, subtype))
+		if err != nil {
+			return nil, err
+		}
+		blk.Work = work
+	}
+
+	return blk, nil
+}
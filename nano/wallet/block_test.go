@@ -0,0 +1,146 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/block"
+	"github.com/alexbakker/gonano/nano/store"
+	"github.com/alexbakker/gonano/nano/store/genesis"
+)
+
+func newTestAccount(t *testing.T, work *WorkOptions) *Account {
+	seed, err := GenerateSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := seed.Key(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acc := NewAccount(key)
+	acc.SetWork(work)
+	return acc
+}
+
+func accountState(t *testing.T, ledger *store.Ledger, address nano.Address) *AccountState {
+	info, err := ledger.GetAddressInfo(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err := ledger.GetRepresentative(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewAccountState(info, rep)
+}
+
+func TestAccountBlocks(t *testing.T) {
+	ctx := context.Background()
+	work := &WorkOptions{
+		Provider:   block.NewLocalWorkProvider(2),
+		Thresholds: block.WorkThresholds{Base: 0xff00000000000000},
+	}
+
+	// create a test network where the genesis account belongs to the wallet
+	genAcc := newTestAccount(t, work)
+	gen := genesis.Genesis{
+		Block: block.OpenBlock{
+			SourceHash:     block.Hash(genAcc.Address()),
+			Representative: genAcc.Address(),
+			Address:        genAcc.Address(),
+		},
+		Balance:        nano.ParseBalanceInts(0, 1000),
+		WorkThresholds: work.Thresholds,
+	}
+	gen.Block.Signature = genAcc.Sign(gen.Block.Hash())
+
+	genWork, err := work.Provider.GenerateWork(ctx, block.Hash(genAcc.Address()), work.Thresholds.Base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen.Block.Work = genWork
+
+	ledger, err := store.NewLedger(store.NewMemoryStore(), store.LedgerOptions{Genesis: gen})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustAdd := func(blk *block.StateBlock, err error) *block.StateBlock {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := ledger.AddBlock(blk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != store.ProcessProgress {
+			t.Fatalf("block %s was rejected: %s", blk.Hash(), res)
+		}
+		return blk
+	}
+	assertBalance := func(address nano.Address, expected nano.Balance) {
+		balance, err := ledger.GetBalance(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !balance.Equal(expected) {
+			t.Fatalf("unexpected balance for %s: %s != %s", address, balance, expected)
+		}
+	}
+
+	acc := newTestAccount(t, work)
+	amount := nano.ParseBalanceInts(0, 100)
+
+	genState := accountState(t, ledger, genAcc.Address())
+	if _, err := genAcc.Send(ctx, genState, acc.Address(), gen.Balance.Add(amount)); err != ErrInsufficientBalance {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := genAcc.Send(ctx, genState, acc.Address(), nano.ZeroBalance); err != ErrZeroAmount {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	send := mustAdd(genAcc.Send(ctx, genState, acc.Address(), amount))
+	genState.Update(send)
+
+	if _, err := acc.Receive(ctx, &AccountState{}, send.Hash(), amount); err != ErrAccountNotOpen {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := acc.Open(ctx, send.Hash(), nano.ZeroBalance, acc.Address()); err != ErrZeroAmount {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	open := mustAdd(acc.Open(ctx, send.Hash(), amount, acc.Address()))
+	assertBalance(acc.Address(), amount)
+
+	rep := newTestAccount(t, nil).Address()
+	state := accountState(t, ledger, acc.Address())
+	mustAdd(acc.ChangeRep(ctx, state, rep))
+	if weight, err := ledger.GetRepresentation(rep); err != nil || !weight.Equal(amount) {
+		t.Fatalf("unexpected weight for the new representative: %s, %v", weight, err)
+	}
+
+	state = accountState(t, ledger, acc.Address())
+	sendBack := mustAdd(acc.Send(ctx, state, genAcc.Address(), amount))
+	if _, err := genAcc.Receive(ctx, genState, sendBack.Hash(), nano.ZeroBalance); err != ErrZeroAmount {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mustAdd(genAcc.Receive(ctx, genState, sendBack.Hash(), amount))
+	assertBalance(acc.Address(), nano.ZeroBalance)
+	assertBalance(genAcc.Address(), gen.Balance)
+
+	// without a work provider, blocks are only signed
+	noWork := newTestAccount(t, nil)
+	blk, err := noWork.Open(ctx, open.Hash(), amount, rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := blk.Hash()
+	if blk.Work != 0 || !noWork.Address().Verify(hash[:], blk.Signature[:]) {
+		t.Fatalf("unexpected block: %+v", blk)
+	}
+}
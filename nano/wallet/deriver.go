@@ -0,0 +1,93 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+
+	"github.com/alexbakker/gonano/nano/crypto/ed25519"
+)
+
+const (
+	// BIP44CoinType is the coin type of Nano in BIP44 derivation paths, as
+	// registered in SLIP-0044.
+	BIP44CoinType = 165
+
+	bip44Purpose  = 44
+	hardenedIndex = 1 << 31
+)
+
+var (
+	ErrBIP44Index = errors.New("BIP44 account indices must be lower than 2^31")
+)
+
+// KeyDeriver derives the private keys of the accounts in a wallet from their
+// index. Seed implements the legacy scheme of the reference wallet, BIP44Seed
+// implements the scheme of hardware wallets.
+type KeyDeriver interface {
+	Key(index uint32) (ed25519.PrivateKey, error)
+}
+
+// BIP44Seed is a seed derived from a BIP39 mnemonic. The keys of its accounts
+// are derived with SLIP-0010 on the BIP44 path 44'/165'/index'.
+type BIP44Seed [MnemonicSeedSize]byte
+
+// NewBIP44Seed derives the seed of the given BIP39 mnemonic and optional
+// passphrase.
+func NewBIP44Seed(mnemonic string, passphrase string) (*BIP44Seed, error) {
+	seedBytes, err := MnemonicSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := new(BIP44Seed)
+	copy(seed[:], seedBytes)
+	wipeBytes(seedBytes)
+	return seed, nil
+}
+
+// Key implements the KeyDeriver interface.
+func (s *BIP44Seed) Key(index uint32) (ed25519.PrivateKey, error) {
+	if index >= hardenedIndex {
+		return nil, ErrBIP44Index
+	}
+
+	path := []uint32{bip44Purpose, BIP44CoinType, index}
+	key, _ := slip10Derive(s[:], path)
+	defer wipeBytes(key)
+
+	_, privKey, err := ed25519.GenerateKey(bytes.NewReader(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return privKey, nil
+}
+
+// slip10Derive derives the private key and chain code at the given path from
+// the given seed as described by SLIP-0010 for ed25519. Only hardened
+// derivation is possible for ed25519, so every index of the path is hardened.
+func slip10Derive(seed []byte, path []uint32) ([]byte, []byte) {
+	mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chainCode := sum[:32], sum[32:]
+
+	for _, index := range path {
+		var indexBytes [4]byte
+		binary.BigEndian.PutUint32(indexBytes[:], index|hardenedIndex)
+
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write([]byte{0})
+		mac.Write(key)
+		mac.Write(indexBytes[:])
+		wipeBytes(key)
+
+		sum = mac.Sum(nil)
+		key, chainCode = sum[:32], sum[32:]
+	}
+
+	return key, chainCode
+}
@@ -1,3 +1,4 @@
-// Package wallet provides some helpful wallet functionality like seed
-// generation and key derivation.
+// Package wallet provides some helpful wallet functionality like seed and BIP39
+// mnemonic generation, key derivation, block construction and encrypted wallet
+// files.
 package wallet
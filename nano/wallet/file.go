@@ -0,0 +1,386 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/crypto/ed25519"
+	"github.com/alexbakker/gonano/nano/crypto/random"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	fileMagic   = "GONANOWL"
+	fileVersion = 2
+	fileSaltLen = 16
+
+	// the key derivation schemes, as stored in version 2 of the wallet file
+	derivationLegacy = 0
+	derivationBIP44  = 1
+)
+
+var (
+	ErrBadWalletFile = errors.New("not a gonano wallet file")
+	ErrBadPassword   = errors.New("bad password or corrupted wallet file")
+	ErrNoPassword    = errors.New("the wallet has no password, save it first")
+
+	// DefaultKDFParams are the Argon2id parameters used for newly saved
+	// wallet files. The parameters are stored in the file, so changing them
+	// doesn't affect existing files.
+	DefaultKDFParams = KDFParams{
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}
+)
+
+// KDFParams are the parameters of the Argon2id key derivation function that
+// derives the encryption key of a wallet file from its password.
+type KDFParams struct {
+	Time uint32
+	// Memory is the amount of memory to use in KiB.
+	Memory  uint32
+	Threads uint8
+}
+
+// fileHeader is the unencrypted part of a wallet file. It's authenticated as
+// the associated data of the encrypted part.
+type fileHeader struct {
+	Magic   [len(fileMagic)]byte
+	Version uint8
+	Params  KDFParams
+	Salt    [fileSaltLen]byte
+	Nonce   [chacha20poly1305.NonceSizeX]byte
+}
+
+// fileCipher is the key that was derived from a password for a wallet file.
+type fileCipher struct {
+	params KDFParams
+	salt   [fileSaltLen]byte
+	key    []byte
+}
+
+// newFileCipher derives a key from the given password with a new salt.
+func newFileCipher(password string, params KDFParams) (*fileCipher, error) {
+	c := fileCipher{params: params}
+	if err := random.Bytes(c.salt[:]); err != nil {
+		return nil, err
+	}
+
+	c.derive(password)
+	return &c, nil
+}
+
+func (c *fileCipher) derive(password string) {
+	c.key = argon2.IDKey([]byte(password), c.salt[:], c.params.Time, c.params.Memory, c.params.Threads, chacha20poly1305.KeySize)
+}
+
+func (c *fileCipher) wipe() {
+	wipeBytes(c.key)
+}
+
+// seal encodes the state of the given wallet and encrypts it.
+func (c *fileCipher) seal(w *Wallet) ([]byte, error) {
+	header := fileHeader{
+		Version: fileVersion,
+		Params:  c.params,
+		Salt:    c.salt,
+	}
+	copy(header.Magic[:], fileMagic)
+	if err := random.Bytes(header.Nonce[:]); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	headerBytes := buf.Bytes()
+
+	aead, err := chacha20poly1305.NewX(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := w.encode()
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(plaintext)
+
+	return aead.Seal(headerBytes, header.Nonce[:], plaintext, headerBytes), nil
+}
+
+// open decrypts the given wallet file contents with the given password.
+func open(data []byte, password string) (*Wallet, *fileCipher, error) {
+	var header fileHeader
+	headerSize := binary.Size(&header)
+	if len(data) < headerSize {
+		return nil, nil, ErrBadWalletFile
+	}
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &header); err != nil {
+		return nil, nil, err
+	}
+	if string(header.Magic[:]) != fileMagic {
+		return nil, nil, ErrBadWalletFile
+	}
+	if header.Version == 0 || header.Version > fileVersion {
+		return nil, nil, fmt.Errorf("unsupported wallet file version: %d", header.Version)
+	}
+	if params := header.Params; params.Time == 0 || params.Threads == 0 || params.Memory < 8*uint32(params.Threads) {
+		return nil, nil, ErrBadWalletFile
+	}
+
+	c := fileCipher{params: header.Params, salt: header.Salt}
+	c.derive(password)
+
+	aead, err := chacha20poly1305.NewX(c.key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plaintext, err := aead.Open(nil, header.Nonce[:], data[headerSize:], data[:headerSize])
+	if err != nil {
+		return nil, nil, ErrBadPassword
+	}
+	defer wipeBytes(plaintext)
+
+	w, err := decode(plaintext, header.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return w, &c, nil
+}
+
+// Save encrypts the wallet with the given password and writes it to the file
+// at the given path. The file is replaced atomically if it already exists.
+// After saving, the wallet can be locked with the given password.
+func (w *Wallet) Save(path string, password string) error {
+	if w.IsLocked() {
+		return ErrLocked
+	}
+
+	c, err := newFileCipher(password, DefaultKDFParams)
+	if err != nil {
+		return err
+	}
+
+	data, err := c.seal(w)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFile(path, data); err != nil {
+		return err
+	}
+
+	if w.cipher != nil {
+		w.cipher.wipe()
+	}
+	w.cipher = c
+	return nil
+}
+
+// Load reads the wallet file at the given path and decrypts it with the given
+// password. ErrBadPassword is returned if the password is wrong.
+func Load(path string, password string) (*Wallet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w, c, err := open(data, password)
+	if err != nil {
+		return nil, err
+	}
+
+	w.cipher = c
+	return w, nil
+}
+
+// ChangePassword changes the password of the wallet file at the given path.
+func ChangePassword(path string, oldPassword string, newPassword string) error {
+	w, err := Load(path, oldPassword)
+	if err != nil {
+		return err
+	}
+
+	return w.Save(path, newPassword)
+}
+
+// writeFile writes data to a temporary file next to the given path and then
+// renames it, so that the wallet file is never left partially written.
+func writeFile(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Chmod(0600)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
+
+// encode encodes the state of the wallet in the plaintext layout of a wallet
+// file: the key deriver, the account index, the default representative, the
+// imported private keys and the labels. The key deriver is encoded as the
+// derivation scheme followed by the seed and, for BIP44, the mnemonic.
+func (w *Wallet) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	switch deriver := w.deriver.(type) {
+	case *Seed:
+		buf.WriteByte(derivationLegacy)
+		buf.Write(deriver[:])
+	case *BIP44Seed:
+		buf.WriteByte(derivationBIP44)
+		buf.Write(deriver[:])
+		binary.Write(&buf, binary.LittleEndian, uint16(len(w.mnemonic)))
+		buf.WriteString(w.mnemonic)
+	default:
+		return nil, fmt.Errorf("unsupported key deriver: %T", w.deriver)
+	}
+	binary.Write(&buf, binary.LittleEndian, w.index)
+	buf.Write(w.rep[:])
+
+	binary.Write(&buf, binary.LittleEndian, uint32(len(w.imported)))
+	for _, acc := range w.imported {
+		buf.Write(acc.privKey)
+	}
+
+	binary.Write(&buf, binary.LittleEndian, uint32(len(w.labels)))
+	for address, label := range w.labels {
+		buf.Write(address[:])
+		binary.Write(&buf, binary.LittleEndian, uint16(len(label)))
+		buf.WriteString(label)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decode decodes a wallet from the plaintext layout of the given version of
+// the wallet file. Version 1 only supports legacy seeds, so it starts with the
+// seed instead of the derivation scheme.
+func decode(data []byte, version uint8) (*Wallet, error) {
+	reader := bytes.NewReader(data)
+
+	derivation := byte(derivationLegacy)
+	if version >= 2 {
+		var err error
+		if derivation, err = reader.ReadByte(); err != nil {
+			return nil, ErrBadWalletFile
+		}
+	}
+
+	var deriver KeyDeriver
+	var mnemonic string
+	switch derivation {
+	case derivationLegacy:
+		seed := new(Seed)
+		if _, err := io.ReadFull(reader, seed[:]); err != nil {
+			return nil, ErrBadWalletFile
+		}
+		deriver = seed
+	case derivationBIP44:
+		seed := new(BIP44Seed)
+		if _, err := io.ReadFull(reader, seed[:]); err != nil {
+			return nil, ErrBadWalletFile
+		}
+
+		var size uint16
+		if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+			return nil, ErrBadWalletFile
+		}
+		mnemonicBytes := make([]byte, size)
+		if _, err := io.ReadFull(reader, mnemonicBytes); err != nil {
+			return nil, ErrBadWalletFile
+		}
+
+		deriver = seed
+		mnemonic = string(mnemonicBytes)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation scheme: %d", derivation)
+	}
+
+	var index uint32
+	if err := binary.Read(reader, binary.LittleEndian, &index); err != nil {
+		return nil, ErrBadWalletFile
+	}
+
+	w, err := newWallet(deriver, mnemonic, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.ReadFull(reader, w.rep[:]); err != nil {
+		return nil, ErrBadWalletFile
+	}
+
+	var count uint32
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return nil, ErrBadWalletFile
+	}
+	for i := uint32(0); i < count; i++ {
+		key := make(ed25519.PrivateKey, ed25519.PrivateKeySize)
+		if _, err := io.ReadFull(reader, key); err != nil {
+			return nil, ErrBadWalletFile
+		}
+		w.imported = append(w.imported, NewAccount(key))
+	}
+
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return nil, ErrBadWalletFile
+	}
+	for i := uint32(0); i < count; i++ {
+		var address nano.Address
+		var size uint16
+		if _, err := io.ReadFull(reader, address[:]); err != nil {
+			return nil, ErrBadWalletFile
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+			return nil, ErrBadWalletFile
+		}
+
+		label := make([]byte, size)
+		if _, err := io.ReadFull(reader, label); err != nil {
+			return nil, ErrBadWalletFile
+		}
+		w.labels[address] = string(label)
+	}
+
+	if reader.Len() != 0 {
+		return nil, ErrBadWalletFile
+	}
+
+	return w, nil
+}
+
+func wipeBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,234 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func init() {
+	// keep the tests fast
+	DefaultKDFParams = KDFParams{Time: 1, Memory: 1024, Threads: 1}
+}
+
+func newTestWallet(t *testing.T) *Wallet {
+	w, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.NewAccount(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Import(newTestAccount(t, nil).privKey); err != nil {
+		t.Fatal(err)
+	}
+
+	addresses := w.Addresses()
+	if err := w.SetLabel(addresses[1], "savings"); err != nil {
+		t.Fatal(err)
+	}
+	w.SetRepresentative(addresses[2])
+	return w
+}
+
+func assertWalletEqual(t *testing.T, w1 *Wallet, w2 *Wallet) {
+	if !reflect.DeepEqual(w1.deriver, w2.deriver) || w1.mnemonic != w2.mnemonic {
+		t.Fatal("key deriver mismatch")
+	}
+	if w1.Index() != w2.Index() || w1.Representative() != w2.Representative() {
+		t.Fatal("wallet mismatch")
+	}
+	if !reflect.DeepEqual(w1.Addresses(), w2.Addresses()) || !reflect.DeepEqual(w1.labels, w2.labels) {
+		t.Fatal("wallet account mismatch")
+	}
+}
+
+func TestWalletFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "wallet")
+	w := newTestWallet(t)
+	if err := w.Save(path, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path, "wrong"); err != ErrBadPassword {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWalletEqual(t, w, loaded)
+
+	if err := ChangePassword(path, "wrong", "new"); err != ErrBadPassword {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ChangePassword(path, "password", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, "password"); err != ErrBadPassword {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded, err = Load(path, "new"); err != nil {
+		t.Fatal(err)
+	}
+	assertWalletEqual(t, w, loaded)
+
+	// any modification of the file must be detected
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{len(fileMagic) + 1, len(fileMagic) + 10, len(data) - 1} {
+		corrupted := append([]byte{}, data...)
+		corrupted[i] ^= 1
+		if _, _, err := open(corrupted, "new"); err == nil {
+			t.Fatalf("unexpected error for corrupted byte %d: %v", i, err)
+		}
+	}
+	if _, _, err := open(data[:10], "new"); err != ErrBadWalletFile {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWalletLock(t *testing.T) {
+	w := newTestWallet(t)
+	if err := w.Lock(); err != ErrNoPassword {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "wallet")
+	if err := w.Save(path, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the wallet is sealed with its current state, not the saved one
+	acc, err := w.NewAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses := w.Addresses()
+	seed, err := w.Seed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if !w.IsLocked() || w.Accounts() != nil || !reflect.DeepEqual(w.Addresses(), addresses) {
+		t.Fatal("the wallet was not locked")
+	}
+	for _, b := range acc.privKey {
+		if b != 0 {
+			t.Fatal("the private key was not wiped")
+		}
+	}
+	if _, err := w.Seed(); err != ErrLocked {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.NewAccount(); err != ErrLocked {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Save(path, "password"); err != ErrLocked {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := w.Unlock("wrong"); err != ErrBadPassword {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if w.IsLocked() || !reflect.DeepEqual(w.Addresses(), addresses) {
+		t.Fatal("the wallet was not unlocked")
+	}
+	if unlockedSeed, err := w.Seed(); err != nil || *unlockedSeed != *seed {
+		t.Fatal("seed mismatch")
+	}
+}
+
+func TestWalletFileMnemonic(t *testing.T) {
+	mnemonic, err := GenerateMnemonic(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewFromMnemonic(mnemonic, "passphrase", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "wallet")
+	if err := w.Save(path, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWalletEqual(t, w, loaded)
+
+	if _, err := loaded.Seed(); err != ErrNoSeed {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := loaded.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.Mnemonic(); err != ErrLocked {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := loaded.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if loadedMnemonic, err := loaded.Mnemonic(); err != nil || loadedMnemonic != mnemonic {
+		t.Fatal("mnemonic mismatch")
+	}
+	assertWalletEqual(t, w, loaded)
+}
+
+func TestWalletFileVersion1(t *testing.T) {
+	w := newTestWallet(t)
+	if _, err := w.Mnemonic(); err != ErrNoMnemonic {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// version 1 is the same as version 2 without the derivation scheme
+	plaintext, err := w.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext[0] != derivationLegacy {
+		t.Fatalf("unexpected derivation scheme: %d", plaintext[0])
+	}
+
+	decoded, err := decode(plaintext[1:], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWalletEqual(t, w, decoded)
+}
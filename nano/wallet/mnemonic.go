@@ -0,0 +1,140 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/alexbakker/gonano/nano/crypto/random"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MnemonicSeedSize is the size of the seed that is derived from a BIP39
+	// mnemonic.
+	MnemonicSeedSize = 64
+
+	// the amount of bits of entropy every word encodes
+	mnemonicWordBits = 11
+	// the amount of PBKDF2 iterations used to derive the seed of a mnemonic
+	mnemonicIterations = 2048
+)
+
+var (
+	ErrEntropyLen       = errors.New("the entropy must be 128 to 256 bits and a multiple of 32 bits")
+	ErrMnemonicLen      = errors.New("a mnemonic must be 12, 15, 18, 21 or 24 words long")
+	ErrMnemonicWord     = errors.New("the mnemonic contains a word that is not in the wordlist")
+	ErrMnemonicChecksum = errors.New("mnemonic checksum mismatch")
+
+	wordIndex = map[string]int{}
+)
+
+func init() {
+	for i, word := range wordlist {
+		wordIndex[word] = i
+	}
+}
+
+// GenerateMnemonic generates a new BIP39 mnemonic with the given amount of
+// bits of entropy. See NewMnemonic.
+func GenerateMnemonic(bits int) (string, error) {
+	if bits%8 != 0 {
+		return "", ErrEntropyLen
+	}
+
+	entropy := make([]byte, bits/8)
+	if err := random.Bytes(entropy); err != nil {
+		return "", err
+	}
+
+	return NewMnemonic(entropy)
+}
+
+// NewMnemonic encodes the given entropy as a BIP39 mnemonic. The entropy must
+// be 16, 20, 24, 28 or 32 bytes long, which results in a mnemonic of 12 up to
+// 24 words.
+func NewMnemonic(entropy []byte) (string, error) {
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", ErrEntropyLen
+	}
+
+	// every word encodes 11 bits of the entropy followed by a checksum of one
+	// bit per 32 bits of entropy
+	bits := len(entropy) * 8
+	checksumBits := bits / 32
+	n := new(big.Int).SetBytes(entropy)
+	n.Lsh(n, uint(checksumBits))
+	n.Or(n, big.NewInt(int64(mnemonicChecksum(entropy)>>(8-checksumBits))))
+
+	words := make([]string, (bits+checksumBits)/mnemonicWordBits)
+	mask := big.NewInt(1<<mnemonicWordBits - 1)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = wordlist[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, mnemonicWordBits)
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicEntropy decodes the given BIP39 mnemonic to the entropy it encodes
+// and verifies its checksum.
+func MnemonicEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, ErrMnemonicLen
+	}
+
+	n := new(big.Int)
+	for _, word := range words {
+		index, ok := wordIndex[word]
+		if !ok {
+			return nil, ErrMnemonicWord
+		}
+
+		n.Lsh(n, mnemonicWordBits)
+		n.Or(n, big.NewInt(int64(index)))
+	}
+
+	checksumBits := len(words) * mnemonicWordBits / 33
+	checksum := byte(new(big.Int).And(n, big.NewInt(1<<uint(checksumBits)-1)).Int64())
+	n.Rsh(n, uint(checksumBits))
+
+	// the entropy may have leading zeroes
+	entropy := make([]byte, checksumBits*4)
+	entropyBytes := n.Bytes()
+	copy(entropy[len(entropy)-len(entropyBytes):], entropyBytes)
+
+	if mnemonicChecksum(entropy)>>(8-checksumBits) != checksum {
+		return nil, ErrMnemonicChecksum
+	}
+
+	return entropy, nil
+}
+
+// ValidateMnemonic checks whether the given string is a valid BIP39 mnemonic.
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicEntropy(mnemonic)
+	return err
+}
+
+// MnemonicSeed validates the given BIP39 mnemonic and derives the seed it
+// represents. The passphrase is optional.
+func MnemonicSeed(mnemonic string, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+
+	password := strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+	salt := "mnemonic" + norm.NFKD.String(passphrase)
+	return pbkdf2.Key([]byte(password), []byte(salt), mnemonicIterations, MnemonicSeedSize, sha512.New), nil
+}
+
+// mnemonicChecksum returns the first byte of the SHA-256 hash of the given
+// entropy. Only its first len(entropy)/4 bits are part of the mnemonic.
+func mnemonicChecksum(entropy []byte) byte {
+	sum := sha256.Sum256(entropy)
+	return sum[0]
+}
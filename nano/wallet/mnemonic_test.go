@@ -0,0 +1,149 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+type mnemonicVector struct {
+	Entropy  string
+	Mnemonic string
+	Seed     string
+}
+
+var (
+	// test vectors from the reference implementation of BIP39, all with
+	// "TREZOR" as the passphrase
+	mnemonicVectors = []mnemonicVector{
+		{
+			Entropy:  "00000000000000000000000000000000",
+			Mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			Seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			Entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			Mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+			Seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			Entropy:  "80808080808080808080808080808080",
+			Mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+			Seed:     "d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+		},
+		{
+			Entropy:  "ffffffffffffffffffffffffffffffff",
+			Mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			Seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+		{
+			Entropy:  "9e885d952ad362caeb4efe34a8e91bd2",
+			Mnemonic: "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+			Seed:     "274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028",
+		},
+		{
+			Entropy:  "0000000000000000000000000000000000000000000000000000000000000000",
+			Mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+			Seed:     "bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+		},
+	}
+)
+
+func TestMnemonic(t *testing.T) {
+	for _, vector := range mnemonicVectors {
+		entropy, err := hex.DecodeString(vector.Entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mnemonic, err := NewMnemonic(entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mnemonic != vector.Mnemonic {
+			t.Fatalf("mnemonic mismatch: %s != %s", mnemonic, vector.Mnemonic)
+		}
+
+		decoded, err := MnemonicEntropy(mnemonic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded, entropy) {
+			t.Fatalf("entropy mismatch: %x != %x", decoded, entropy)
+		}
+
+		seed, err := MnemonicSeed(mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(seed) != vector.Seed {
+			t.Fatalf("seed mismatch: %x != %s", seed, vector.Seed)
+		}
+	}
+}
+
+func TestMnemonicInvalid(t *testing.T) {
+	mnemonics := map[string]error{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon":         ErrMnemonicLen,
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon": ErrMnemonicChecksum,
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon gonano":  ErrMnemonicWord,
+	}
+
+	for mnemonic, expected := range mnemonics {
+		if err := ValidateMnemonic(mnemonic); err != expected {
+			t.Fatalf("unexpected error for %q: %v", mnemonic, err)
+		}
+	}
+
+	if _, err := NewMnemonic(make([]byte, 15)); err != ErrEntropyLen {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, bits := range []int{128, 160, 192, 224, 256} {
+		mnemonic, err := GenerateMnemonic(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateMnemonic(mnemonic); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSLIP10(t *testing.T) {
+	// test vector 1 for ed25519 from SLIP-0010
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	key, chainCode := slip10Derive(seed, []uint32{0, 1, 2})
+	if hex.EncodeToString(key) != "92a5b23c0b8a99e37d07df3fb9966917f5d06e02ddbd909c7e184371463e9fc9" {
+		t.Fatalf("private key mismatch: %x", key)
+	}
+	if hex.EncodeToString(chainCode) != "2e69929e00b5ab250f49c3fb1c12f252de4fed2c1db88387094a0f8c4c9ccd6c" {
+		t.Fatalf("chain code mismatch: %x", chainCode)
+	}
+}
+
+func TestBIP44Seed(t *testing.T) {
+	mnemonic := "edge defense waste choose enrich upon flee junk siren film clown finish luggage leader kid quick brick print evidence swap drill paddle truly occur"
+	seed, err := NewBIP44Seed(mnemonic, "some password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(seed[:]) != "0dc285fde768f7ff29b66ce7252d56ed92fe003b605907f7a4f683c3dc8586d34a914d3c71fc099bb38ee4a59e5b081a3497b7a323e90cc68f67b5837690310c" {
+		t.Fatalf("seed mismatch: %x", seed[:])
+	}
+
+	key, err := seed.Key(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(key[:32]) != "3be4fc2ef3f3b7374e6fc4fb6e7bb153f8a2998b3b3dab50853eabe128024143" {
+		t.Fatalf("private key mismatch: %x", key[:32])
+	}
+	if address := NewAccount(key).Address().String(); address != "nano_1pu7p5n3ghq1i1p4rhmek41f5add1uh34xpb94nkbxe8g4a6x1p69emk8y1d" {
+		t.Fatalf("address mismatch: %s", address)
+	}
+
+	if _, err := seed.Key(hardenedIndex); err != ErrBIP44Index {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 
 	"github.com/alexbakker/gonano/nano/crypto/ed25519"
 	"github.com/alexbakker/gonano/nano/crypto/random"
@@ -25,6 +26,22 @@ func GenerateSeed() (*Seed, error) {
 	return seed, nil
 }
 
+// ParseSeed parses the given hex-encoded seed.
+func ParseSeed(s string) (*Seed, error) {
+	seedBytes, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(seedBytes) != SeedSize {
+		return nil, fmt.Errorf("bad seed size: %d", len(seedBytes))
+	}
+
+	seed := new(Seed)
+	copy(seed[:], seedBytes)
+	return seed, nil
+}
+
 func (s *Seed) Key(index uint32) (ed25519.PrivateKey, error) {
 	indexBytes := make([]byte, 4)
 	binary.BigEndian.PutUint32(indexBytes, index)
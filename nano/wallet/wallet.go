@@ -1,27 +1,71 @@
 package wallet
 
+import (
+	"errors"
+	"math"
+
+	"github.com/alexbakker/gonano/nano"
+	"github.com/alexbakker/gonano/nano/crypto/ed25519"
+)
+
+var (
+	ErrLocked     = errors.New("the wallet is locked")
+	ErrLabelLen   = errors.New("the label is too long")
+	ErrNoSeed     = errors.New("the wallet doesn't have a legacy seed")
+	ErrNoMnemonic = errors.New("the wallet doesn't have a mnemonic")
+)
+
 type Wallet struct {
-	seed     *Seed
+	// deriver is either a *Seed or a *BIP44Seed, in which case mnemonic is
+	// the mnemonic it was derived from
+	deriver  KeyDeriver
+	mnemonic string
 	accounts []*Account
 	index    uint32
+	imported []*Account
+	labels   map[nano.Address]string
+	rep      nano.Address
+
+	// cipher is derived from the password the wallet was last saved or loaded
+	// with, sealed holds the encrypted secrets while the wallet is locked
+	cipher *fileCipher
+	sealed []byte
+	locked []nano.Address
 }
 
+// New creates a wallet that derives its accounts from the given seed with the
+// legacy scheme of the reference wallet. The accounts up to and including the
+// given index are derived right away.
 func New(seed *Seed, index uint32) (*Wallet, error) {
-	accounts := []*Account{}
-	for i := uint32(0); i < index+1; i++ {
-		key, err := seed.Key(i)
-		if err != nil {
-			return nil, err
-		}
+	seedCopy := *seed
+	return newWallet(&seedCopy, "", index)
+}
 
-		accounts = append(accounts, NewAccount(key))
+// NewFromMnemonic creates a wallet that derives its accounts from the given
+// BIP39 mnemonic and optional passphrase with BIP44. The accounts up to and
+// including the given index are derived right away.
+func NewFromMnemonic(mnemonic string, passphrase string, index uint32) (*Wallet, error) {
+	seed, err := NewBIP44Seed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Wallet{
-		seed:     seed,
-		accounts: accounts,
+	return newWallet(seed, mnemonic, index)
+}
+
+func newWallet(deriver KeyDeriver, mnemonic string, index uint32) (*Wallet, error) {
+	w := &Wallet{
+		deriver:  deriver,
+		mnemonic: mnemonic,
 		index:    index,
-	}, nil
+		labels:   map[nano.Address]string{},
+	}
+
+	if err := w.deriveAccounts(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
 }
 
 func Generate() (*Wallet, error) {
@@ -34,8 +78,228 @@ func Generate() (*Wallet, error) {
 	return New(seed, 0)
 }
 
+// deriveAccounts derives the accounts up to and including the current index
+// with the key deriver.
+func (w *Wallet) deriveAccounts() error {
+	accounts := []*Account{}
+	for i := uint32(0); i < w.index+1; i++ {
+		key, err := w.deriver.Key(i)
+		if err != nil {
+			return err
+		}
+
+		accounts = append(accounts, NewAccount(key))
+	}
+
+	w.accounts = accounts
+	return nil
+}
+
+// Accounts returns the accounts derived from the seed, followed by the
+// imported accounts. It returns nil if the wallet is locked.
 func (w *Wallet) Accounts() []*Account {
-	accounts := make([]*Account, len(w.accounts))
-	copy(accounts, w.accounts)
+	if w.IsLocked() {
+		return nil
+	}
+
+	accounts := make([]*Account, 0, len(w.accounts)+len(w.imported))
+	accounts = append(accounts, w.accounts...)
+	accounts = append(accounts, w.imported...)
 	return accounts
 }
+
+// Addresses returns the addresses of the accounts in the wallet, in the same
+// order as Accounts. Unlike Accounts, it also works if the wallet is locked.
+func (w *Wallet) Addresses() []nano.Address {
+	if w.IsLocked() {
+		addresses := make([]nano.Address, len(w.locked))
+		copy(addresses, w.locked)
+		return addresses
+	}
+
+	var addresses []nano.Address
+	for _, acc := range w.Accounts() {
+		addresses = append(addresses, acc.Address())
+	}
+	return addresses
+}
+
+// Account returns the account with the given address, or nil if it's not in
+// the wallet.
+func (w *Wallet) Account(address nano.Address) *Account {
+	for _, acc := range w.Accounts() {
+		if acc.Address() == address {
+			return acc
+		}
+	}
+
+	return nil
+}
+
+// Seed returns the legacy seed of the wallet. ErrNoSeed is returned if the
+// wallet was created from a mnemonic.
+func (w *Wallet) Seed() (*Seed, error) {
+	if w.IsLocked() {
+		return nil, ErrLocked
+	}
+
+	legacySeed, ok := w.deriver.(*Seed)
+	if !ok {
+		return nil, ErrNoSeed
+	}
+
+	seed := *legacySeed
+	return &seed, nil
+}
+
+// Mnemonic returns the BIP39 mnemonic of the wallet. ErrNoMnemonic is returned
+// if the wallet was created from a legacy seed.
+func (w *Wallet) Mnemonic() (string, error) {
+	if w.IsLocked() {
+		return "", ErrLocked
+	}
+	if w.mnemonic == "" {
+		return "", ErrNoMnemonic
+	}
+
+	return w.mnemonic, nil
+}
+
+// Index returns the index of the last account that was derived with the key
+// deriver.
+func (w *Wallet) Index() uint32 {
+	return w.index
+}
+
+// NewAccount derives the account at the next index with the key deriver.
+func (w *Wallet) NewAccount() (*Account, error) {
+	if w.IsLocked() {
+		return nil, ErrLocked
+	}
+
+	key, err := w.deriver.Key(w.index + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	acc := NewAccount(key)
+	w.accounts = append(w.accounts, acc)
+	w.index++
+	return acc, nil
+}
+
+// Import adds an account with the given private key to the wallet. If the
+// account is already in the wallet, the existing account is returned.
+func (w *Wallet) Import(key ed25519.PrivateKey) (*Account, error) {
+	if w.IsLocked() {
+		return nil, ErrLocked
+	}
+
+	acc := NewAccount(key)
+	if existing := w.Account(acc.Address()); existing != nil {
+		return existing, nil
+	}
+
+	w.imported = append(w.imported, acc)
+	return acc, nil
+}
+
+// Label returns the label of the given address, or an empty string if it
+// doesn't have one.
+func (w *Wallet) Label(address nano.Address) string {
+	return w.labels[address]
+}
+
+// SetLabel sets the label of the given address. An empty label removes it.
+func (w *Wallet) SetLabel(address nano.Address, label string) error {
+	if len(label) > math.MaxUint16 {
+		return ErrLabelLen
+	}
+
+	if label == "" {
+		delete(w.labels, address)
+	} else {
+		w.labels[address] = label
+	}
+
+	return nil
+}
+
+// Representative returns the default representative for new accounts.
+func (w *Wallet) Representative() nano.Address {
+	return w.rep
+}
+
+// SetRepresentative sets the default representative for new accounts.
+func (w *Wallet) SetRepresentative(rep nano.Address) {
+	w.rep = rep
+}
+
+// IsLocked reports whether the wallet is locked.
+func (w *Wallet) IsLocked() bool {
+	return w.sealed != nil
+}
+
+// Lock encrypts the seed and private keys of the wallet with the password it
+// was last saved or loaded with and removes them from memory. Accounts that
+// were obtained from the wallet before locking it can no longer be used.
+func (w *Wallet) Lock() error {
+	if w.IsLocked() {
+		return nil
+	}
+	if w.cipher == nil {
+		return ErrNoPassword
+	}
+
+	sealed, err := w.cipher.seal(w)
+	if err != nil {
+		return err
+	}
+
+	w.locked = w.Addresses()
+	w.sealed = sealed
+	w.cipher.wipe()
+	w.cipher = nil
+
+	switch deriver := w.deriver.(type) {
+	case *Seed:
+		wipeBytes(deriver[:])
+	case *BIP44Seed:
+		wipeBytes(deriver[:])
+	}
+	w.deriver = nil
+	w.mnemonic = ""
+	for _, acc := range w.accounts {
+		acc.wipe()
+	}
+	for _, acc := range w.imported {
+		acc.wipe()
+	}
+	w.accounts = nil
+	w.imported = nil
+	return nil
+}
+
+// Unlock decrypts the seed and private keys of a locked wallet with the given
+// password.
+func (w *Wallet) Unlock(password string) error {
+	if !w.IsLocked() {
+		return nil
+	}
+
+	unlocked, cipher, err := open(w.sealed, password)
+	if err != nil {
+		return err
+	}
+
+	// the labels and representative may have changed while the wallet was
+	// locked, so only the secrets are restored
+	w.deriver = unlocked.deriver
+	w.mnemonic = unlocked.mnemonic
+	w.accounts = unlocked.accounts
+	w.imported = unlocked.imported
+	w.cipher = cipher
+	w.sealed = nil
+	w.locked = nil
+	return nil
+}
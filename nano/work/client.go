@@ -0,0 +1,141 @@
+package work
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+const (
+	// the time the client waits for the work server to acknowledge a
+	// work_cancel request after a work_generate request was canceled
+	cancelTimeout = 5 * time.Second
+)
+
+var (
+	ErrBadServerWork = errors.New("the work server returned an invalid work value")
+)
+
+// Client generates work values on a remote work server. It implements the
+// block.WorkProvider interface.
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+// NewClient creates a new client for the work server at the given URL.
+func NewClient(url string) *Client {
+	return &Client{
+		url:    url,
+		client: &http.Client{},
+	}
+}
+
+// GenerateWork implements the block.WorkProvider interface. If ctx is done
+// before the work server responds, the request is canceled on the work server
+// with work_cancel as well.
+func (c *Client) GenerateWork(ctx context.Context, root block.Hash, difficulty uint64) (block.Work, error) {
+	req := generateRequest{
+		Action:     "work_generate",
+		Hash:       root,
+		Difficulty: (*Difficulty)(&difficulty),
+	}
+
+	var res generateResponse
+	if err := c.call(ctx, &req, &res); err != nil {
+		if ctx.Err() != nil {
+			cancelCtx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+			defer cancel()
+
+			if err := c.Cancel(cancelCtx, root); err != nil {
+				fmt.Printf("error canceling work generation: %s\n", err)
+			}
+			return 0, ctx.Err()
+		}
+		return 0, err
+	}
+
+	// don't trust the work server blindly
+	if !res.Work.Valid(root, difficulty) {
+		return 0, ErrBadServerWork
+	}
+
+	return res.Work, nil
+}
+
+// Cancel cancels all work_generate requests for the given root on the work
+// server.
+func (c *Client) Cancel(ctx context.Context, root block.Hash) error {
+	req := cancelRequest{
+		Action: "work_cancel",
+		Hash:   root,
+	}
+
+	var res cancelResponse
+	return c.call(ctx, &req, &res)
+}
+
+// Validate asks the work server whether the given work value meets the given
+// difficulty for the given root. It also returns the difficulty of the work
+// value.
+func (c *Client) Validate(ctx context.Context, root block.Hash, work block.Work, difficulty uint64) (bool, uint64, error) {
+	req := validateRequest{
+		Action:     "work_validate",
+		Hash:       root,
+		Work:       work,
+		Difficulty: (*Difficulty)(&difficulty),
+	}
+
+	var res validateResponse
+	if err := c.call(ctx, &req, &res); err != nil {
+		return false, 0, err
+	}
+
+	return res.Valid == "1", uint64(res.Difficulty), nil
+}
+
+// call sends the given request to the work server and decodes the response
+// into res. If the response has an error field, it's returned as an error.
+func (c *Client) call(ctx context.Context, req interface{}, res interface{}) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpRes, err := c.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+
+	data, err = ioutil.ReadAll(httpRes.Body)
+	if err != nil {
+		return err
+	}
+	if httpRes.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected work server status: %s", httpRes.Status)
+	}
+
+	var errRes errorResponse
+	if err := json.Unmarshal(data, &errRes); err != nil {
+		return err
+	}
+	if errRes.Error != "" {
+		return fmt.Errorf("work server error: %s", errRes.Error)
+	}
+
+	return json.Unmarshal(data, res)
+}
@@ -0,0 +1,4 @@
+// Package work implements the JSON protocol of Nano work servers: a client
+// that generates work values on a remote work server and a server that
+// generates them in this process.
+package work
@@ -0,0 +1,105 @@
+package work
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+// Difficulty is a work difficulty that is encoded as a hexadecimal string, as
+// work servers do.
+type Difficulty uint64
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (d Difficulty) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (d *Difficulty) UnmarshalText(text []byte) error {
+	if len(text) != hex.EncodedLen(8) {
+		return fmt.Errorf("bad difficulty length: %d", len(text))
+	}
+
+	var bytes [8]byte
+	if _, err := hex.Decode(bytes[:], text); err != nil {
+		return err
+	}
+
+	*d = Difficulty(binary.BigEndian.Uint64(bytes[:]))
+	return nil
+}
+
+// String implements the fmt.Stringer interface.
+func (d Difficulty) String() string {
+	var bytes [8]byte
+	binary.BigEndian.PutUint64(bytes[:], uint64(d))
+	return hex.EncodeToString(bytes[:])
+}
+
+// Multiplier is a difficulty multiplier that is encoded as a decimal string.
+type Multiplier float64
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (m Multiplier) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatFloat(float64(m), 'f', -1, 64)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (m *Multiplier) UnmarshalText(text []byte) error {
+	f, err := strconv.ParseFloat(string(text), 64)
+	if err != nil {
+		return err
+	}
+	if f <= 0 {
+		return fmt.Errorf("bad multiplier: %s", text)
+	}
+
+	*m = Multiplier(f)
+	return nil
+}
+
+type request struct {
+	Action string `json:"action"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type generateRequest struct {
+	Action     string      `json:"action"`
+	Hash       block.Hash  `json:"hash"`
+	Difficulty *Difficulty `json:"difficulty,omitempty"`
+	Multiplier *Multiplier `json:"multiplier,omitempty"`
+}
+
+type generateResponse struct {
+	Work       block.Work `json:"work"`
+	Difficulty Difficulty `json:"difficulty"`
+	Multiplier Multiplier `json:"multiplier"`
+}
+
+type cancelRequest struct {
+	Action string     `json:"action"`
+	Hash   block.Hash `json:"hash"`
+}
+
+type cancelResponse struct{}
+
+type validateRequest struct {
+	Action     string      `json:"action"`
+	Hash       block.Hash  `json:"hash"`
+	Work       block.Work  `json:"work"`
+	Difficulty *Difficulty `json:"difficulty,omitempty"`
+	Multiplier *Multiplier `json:"multiplier,omitempty"`
+}
+
+type validateResponse struct {
+	Valid      string     `json:"valid"`
+	Difficulty Difficulty `json:"difficulty"`
+	Multiplier Multiplier `json:"multiplier"`
+}
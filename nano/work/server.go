@@ -0,0 +1,196 @@
+package work
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+const (
+	maxRequestSize = 1 << 16
+)
+
+var (
+	ErrUnknownAction = errors.New("unknown command")
+	ErrCanceled      = errors.New("work generation was canceled")
+)
+
+type actionFunc func(s *Server, ctx context.Context, data []byte) (interface{}, error)
+
+var (
+	actions = map[string]actionFunc{
+		"work_generate": (*Server).generate,
+		"work_cancel":   (*Server).cancel,
+		"work_validate": (*Server).validate,
+	}
+)
+
+// Server is an HTTP server that generates work values with a WorkProvider in
+// response to the JSON requests of the work server protocol. Every request is
+// a JSON object with an "action" field, the response is a JSON object as well.
+// Errors are reported in the "error" field of the response.
+type Server struct {
+	provider block.WorkProvider
+	base     uint64
+
+	mutex sync.Mutex
+	jobs  map[block.Hash]map[*job]struct{}
+}
+
+// job is a work_generate request that is in progress.
+type job struct {
+	cancel context.CancelFunc
+}
+
+// NewServer creates a new work server that generates work values with the
+// given provider. The base difficulty is used for requests that don't specify
+// a difficulty, and multipliers are relative to it.
+func NewServer(provider block.WorkProvider, base uint64) *Server {
+	return &Server{
+		provider: provider,
+		base:     base,
+		jobs:     map[block.Hash]map[*job]struct{}{},
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	res, err := s.handle(r.Context(), data)
+	if err != nil {
+		res = &errorResponse{Error: err.Error()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		fmt.Printf("error writing work server response: %s\n", err)
+	}
+}
+
+func (s *Server) handle(ctx context.Context, data []byte) (interface{}, error) {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	action, ok := actions[req.Action]
+	if !ok {
+		return nil, ErrUnknownAction
+	}
+
+	return action(s, ctx, data)
+}
+
+// difficulty returns the difficulty of a request, which may be given as a
+// difficulty or as a multiplier of the base difficulty.
+func (s *Server) difficulty(difficulty *Difficulty, multiplier *Multiplier) uint64 {
+	switch {
+	case difficulty != nil:
+		return uint64(*difficulty)
+	case multiplier != nil:
+		return block.MultiplierToDifficulty(float64(*multiplier), s.base)
+	default:
+		return s.base
+	}
+}
+
+func (s *Server) generate(ctx context.Context, data []byte) (interface{}, error) {
+	var req generateRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// keep track of the request so that it can be canceled with work_cancel
+	j := &job{cancel: cancel}
+	s.addJob(req.Hash, j)
+	defer s.removeJob(req.Hash, j)
+
+	work, err := s.provider.GenerateWork(ctx, req.Hash, s.difficulty(req.Difficulty, req.Multiplier))
+	if err != nil {
+		if err == context.Canceled {
+			return nil, ErrCanceled
+		}
+		return nil, err
+	}
+
+	difficulty := work.Difficulty(req.Hash)
+	return &generateResponse{
+		Work:       work,
+		Difficulty: Difficulty(difficulty),
+		Multiplier: Multiplier(block.DifficultyToMultiplier(difficulty, s.base)),
+	}, nil
+}
+
+func (s *Server) cancel(ctx context.Context, data []byte) (interface{}, error) {
+	var req cancelRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for j := range s.jobs[req.Hash] {
+		j.cancel()
+	}
+
+	return &cancelResponse{}, nil
+}
+
+func (s *Server) validate(ctx context.Context, data []byte) (interface{}, error) {
+	var req validateRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	res := validateResponse{Valid: "0"}
+	difficulty := req.Work.Difficulty(req.Hash)
+	if difficulty >= s.difficulty(req.Difficulty, req.Multiplier) {
+		res.Valid = "1"
+	}
+	res.Difficulty = Difficulty(difficulty)
+	res.Multiplier = Multiplier(block.DifficultyToMultiplier(difficulty, s.base))
+
+	return &res, nil
+}
+
+func (s *Server) addJob(hash block.Hash, j *job) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobs, ok := s.jobs[hash]
+	if !ok {
+		jobs = map[*job]struct{}{}
+		s.jobs[hash] = jobs
+	}
+	jobs[j] = struct{}{}
+}
+
+func (s *Server) removeJob(hash block.Hash, j *job) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.jobs[hash], j)
+	if len(s.jobs[hash]) == 0 {
+		delete(s.jobs, hash)
+	}
+}
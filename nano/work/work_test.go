@@ -0,0 +1,114 @@
+package work
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexbakker/gonano/nano/block"
+)
+
+const (
+	testBase = uint64(0xfff0000000000000)
+	// no work value meets this difficulty
+	testImpossible = ^uint64(0)
+)
+
+func initTestServer(t *testing.T) (*Server, *Client, func()) {
+	server := NewServer(block.NewLocalWorkProvider(2), testBase)
+	httpServer := httptest.NewServer(server)
+	return server, NewClient(httpServer.URL), httpServer.Close
+}
+
+// waitJobs waits until the server has the given amount of jobs in progress for
+// the given root.
+func waitJobs(t *testing.T, server *Server, root block.Hash, count int) {
+	for i := 0; i < 500; i++ {
+		server.mutex.Lock()
+		n := len(server.jobs[root])
+		server.mutex.Unlock()
+
+		if n == count {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("the server doesn't have %d jobs for %s", count, root)
+}
+
+func TestWorkServer(t *testing.T) {
+	_, client, cleanup := initTestServer(t)
+	defer cleanup()
+
+	var root block.Hash
+	copy(root[:], "work server test")
+
+	work, err := client.GenerateWork(context.Background(), root, testBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !work.Valid(root, testBase) {
+		t.Fatalf("work not valid: %s", work)
+	}
+
+	valid, difficulty, err := client.Validate(context.Background(), root, work, testBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid || difficulty != work.Difficulty(root) {
+		t.Fatalf("unexpected validation result: %t, %x", valid, difficulty)
+	}
+
+	valid, _, err = client.Validate(context.Background(), root, work, testImpossible)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Fatal("work is valid for an impossible difficulty")
+	}
+
+	var res cancelResponse
+	if err := client.call(context.Background(), &request{Action: "work_foo"}, &res); err == nil {
+		t.Fatal("expected an error for an unknown action")
+	}
+}
+
+func TestWorkServerCancel(t *testing.T) {
+	server, client, cleanup := initTestServer(t)
+	defer cleanup()
+
+	var root block.Hash
+	copy(root[:], "work server cancel test")
+
+	// cancel a request with work_cancel
+	errs := make(chan error)
+	go func() {
+		_, err := client.GenerateWork(context.Background(), root, testImpossible)
+		errs <- err
+	}()
+
+	waitJobs(t, server, root, 1)
+	if err := client.Cancel(context.Background(), root); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err == nil {
+		t.Fatal("expected an error for a canceled request")
+	}
+	waitJobs(t, server, root, 0)
+
+	// cancel a request by canceling its context
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := client.GenerateWork(ctx, root, testImpossible)
+		errs <- err
+	}()
+
+	waitJobs(t, server, root, 1)
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitJobs(t, server, root, 0)
+}